@@ -0,0 +1,184 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// TokenValidation defines how the access tokens are validated by the Authenticate method.
+type TokenValidation int
+
+const (
+	// IntrospectionValidation checks every access token with the introspection endpoint.
+	IntrospectionValidation TokenValidation = iota
+
+	// LocalValidation checks the JWT access tokens locally with the key set of the authorization server.
+	LocalValidation
+
+	// HybridValidation checks the JWT access tokens locally and the opaque access tokens with
+	// the introspection endpoint.
+	HybridValidation
+)
+
+// AnyClient is used in the Config.AcceptedClients to accept the access tokens of any client,
+// usually together with the Config.Audience.
+const AnyClient = "*"
+
+// jwtAccessToken repeats the structure of the JWT access token claims.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc9068#section-2.2
+type jwtAccessToken struct {
+	Act      *Actor                 `json:"act"`
+	Aud      Audience               `json:"aud"`
+	ClientID string                 `json:"client_id"`
+	Exp      int64                  `json:"exp"`
+	Ext      map[string]interface{} `json:"ext"`
+	Iat      int                    `json:"iat"`
+	Iss      string                 `json:"iss"`
+	Nbf      int                    `json:"nbf"`
+	Scope    string                 `json:"scope"`
+	Scp      []string               `json:"scp"`
+	Sub      string                 `json:"sub"`
+	Username string                 `json:"username"`
+}
+
+// Authenticate checks the access token with the method selected by the Config.TokenValidation and
+// returns its introspection in the event of its validity.
+func (j *JwtVerifier) Authenticate(ctx context.Context, token string) (*IntrospectToken, error) {
+	switch j.config.TokenValidation {
+	case LocalValidation:
+		return j.VerifyAccessToken(ctx, token)
+	case HybridValidation:
+		if isJwt(token) {
+			return j.VerifyAccessToken(ctx, token)
+		}
+	}
+	return j.Introspect(ctx, token)
+}
+
+// VerifyAccessToken checks the JWT access token locally, without the request to the introspection
+// endpoint, and returns its claims in the same shape as Introspect does.
+//
+// The token signature is verified with the cached key set of the authorization server and
+// the typ header, exp, nbf, iat, iss, aud and client_id claims are checked as described in RFC 9068.
+// The aud claim must contain one of the Config.Audience, or the Config.ClientID if the audience isn't configured.
+// Every failed check is returned as *ValidationError wrapping one of the sentinel errors.
+func (j *JwtVerifier) VerifyAccessToken(ctx context.Context, token string) (*IntrospectToken, error) {
+	verified, h, err := j.verifySignature(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if typ := strings.ToLower(h.Typ); typ != "at+jwt" && typ != "application/at+jwt" {
+		return nil, validationError("typ", ErrInvalidTokenType)
+	}
+
+	t := &jwtAccessToken{}
+	if err := json.Unmarshal(verified, t); err != nil {
+		return nil, err
+	}
+
+	if t.Iss != j.config.Issuer {
+		return nil, validationError("iss", ErrInvalidIssuer)
+	}
+
+	now := j.now()
+	if t.Exp == 0 {
+		return nil, validationError("exp", ErrMissingClaim)
+	}
+	if now.After(time.Unix(t.Exp, 0).Add(j.config.Leeway)) {
+		return nil, validationError("exp", ErrTokenExpired)
+	}
+	if t.Nbf != 0 && time.Unix(int64(t.Nbf), 0).After(now.Add(j.config.Leeway)) {
+		return nil, validationError("nbf", ErrTokenNotValidYet)
+	}
+	if t.Iat != 0 && time.Unix(int64(t.Iat), 0).After(now.Add(j.config.Leeway)) {
+		return nil, validationError("iat", ErrTokenUsedBeforeIssued)
+	}
+
+	// RFC 9068 requires the aud claim, so without the Config.Audience the token must be issued for the client.
+	if len(j.config.Audience) == 0 && !t.Aud.Contains(j.config.ClientID) {
+		return nil, validationError("aud", ErrInvalidAudience)
+	}
+	if t.ClientID == "" {
+		return nil, validationError("client_id", ErrMissingClaim)
+	}
+	if err := j.checkAudienceAndClient(t.Aud, t.ClientID); err != nil {
+		claim := "client_id"
+		if errors.Is(err, ErrInvalidAudience) {
+			claim = "aud"
+		}
+		return nil, validationError(claim, err)
+	}
+
+	scope := t.Scope
+	if scope == "" {
+		scope = strings.Join(t.Scp, " ")
+	}
+	introspect := &IntrospectToken{
+		Act:       t.Act,
+		Active:    true,
+		Aud:       t.Aud,
+		ClientID:  t.ClientID,
+		Exp:       t.Exp,
+		Ext:       t.Ext,
+		Iat:       t.Iat,
+		Iss:       t.Iss,
+		Nbf:       t.Nbf,
+		Scope:     scope,
+		Sub:       t.Sub,
+		TokenType: "access_token",
+		Username:  t.Username,
+	}
+	if err := j.applyTokenPolicy(ctx, introspect); err != nil {
+		return nil, err
+	}
+	return introspect, nil
+}
+
+// checkAudienceAndClient checks the audience and the client of the access token against the Config.Audience
+// and Config.AcceptedClients. Without them only the access tokens of the Config.ClientID are accepted.
+func (j *JwtVerifier) checkAudienceAndClient(aud Audience, clientID string) error {
+	if len(j.config.Audience) > 0 && !containsAny(aud, j.config.Audience) {
+		return ErrInvalidAudience
+	}
+	// The token without the client is rejected even if the ClientID of the resource server is empty too.
+	if clientID == "" {
+		return ErrInvalidClient
+	}
+	if clientID == j.config.ClientID {
+		return nil
+	}
+	for _, c := range j.config.AcceptedClients {
+		if c == clientID || c == AnyClient {
+			return nil
+		}
+	}
+	return ErrInvalidClient
+}
+
+// applyTokenPolicy makes the custom decision about the access token with the Config.TokenPolicy.
+func (j *JwtVerifier) applyTokenPolicy(ctx context.Context, t *IntrospectToken) error {
+	if j.config.TokenPolicy == nil {
+		return nil
+	}
+	return j.config.TokenPolicy(ctx, t)
+}
+
+// isJwt checks that the token looks like a signed JWT rather than an opaque token.
+func isJwt(token string) bool {
+	h, err := parseTokenHeader(token)
+	return err == nil && h.Alg != ""
+}
+
+func containsAny(aud Audience, expected []string) bool {
+	for _, e := range expected {
+		if aud.Contains(e) {
+			return true
+		}
+	}
+	return false
+}
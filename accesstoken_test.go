@@ -0,0 +1,124 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func accessTokenClaims(issuer string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":       issuer,
+		"sub":       "user_id",
+		"aud":       []string{"api"},
+		"client_id": "CLIENT_ID",
+		"exp":       now.Add(time.Hour).Unix(),
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"scp":       []string{"openid", "offline"},
+		"ext":       map[string]interface{}{"role": "admin"},
+	}
+}
+
+func TestVerifyAccessToken(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Audience: []string{"api"}})
+	tok, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "at+jwt", accessTokenClaims(ts.URL)))
+	if err != nil {
+		t.Fatalf("unable to verify access token: %s", err)
+	}
+	if !tok.Active || tok.Sub != "user_id" || tok.ClientID != "CLIENT_ID" || tok.Scope != "openid offline" {
+		t.Errorf("Unexpected introspection of access token: %#v", tok)
+	}
+	if tok.Ext["role"] != "admin" {
+		t.Errorf("Unexpected extra claims of access token: %#v", tok.Ext)
+	}
+}
+
+func TestVerifyAccessToken_InvalidClaims(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Audience: []string{"api"}})
+	now := time.Now()
+
+	for _, tc := range []struct {
+		claim string
+		value interface{}
+		err   error
+	}{
+		{claim: "iss", value: "http://another.issuer", err: ErrInvalidIssuer},
+		{claim: "aud", value: "another", err: ErrInvalidAudience},
+		{claim: "client_id", value: "CLIENT_ID2", err: ErrInvalidClient},
+		{claim: "exp", value: now.Add(-time.Minute).Unix(), err: ErrTokenExpired},
+		{claim: "nbf", value: now.Add(time.Minute).Unix(), err: ErrTokenNotValidYet},
+	} {
+		claims := accessTokenClaims(ts.URL)
+		claims[tc.claim] = tc.value
+		_, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "at+jwt", claims))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Invalid error for %s claim [%v], must be [%s]", tc.claim, err, tc.err)
+		}
+	}
+
+	_, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "JWT", accessTokenClaims(ts.URL)))
+	if !errors.Is(err, ErrInvalidTokenType) {
+		t.Errorf("Invalid error for token type [%v], must be [%s]", err, ErrInvalidTokenType)
+	}
+}
+
+func TestVerifyAccessToken_DefaultAudience(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL})
+	claims := accessTokenClaims(ts.URL)
+	_, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "at+jwt", claims))
+	var ve *ValidationError
+	if !errors.Is(err, ErrInvalidAudience) || !errors.As(err, &ve) || ve.Claim != "aud" {
+		t.Errorf("Invalid error for another audience without configured one [%v], must be [%s]", err, ErrInvalidAudience)
+	}
+
+	claims["aud"] = "CLIENT_ID"
+	if _, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "at+jwt", claims)); err != nil {
+		t.Errorf("Token issued for the client must be valid without configured audience: %s", err)
+	}
+}
+
+func TestAuthenticate_Hybrid(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	introspected := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/introspect":
+			introspected++
+			w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","aud":["api"],"sub":"opaque_user"}`))
+		default:
+			w.Write(jwksBody(t, signer))
+		}
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Audience: []string{"api"}, TokenValidation: HybridValidation})
+
+	tok, err := jwt.Authenticate(context.Background(), signer.signTyped(t, "at+jwt", accessTokenClaims(ts.URL)))
+	if err != nil || tok.Sub != "user_id" {
+		t.Errorf("unable to authenticate JWT access token: %v", err)
+	}
+	tok, err = jwt.Authenticate(context.Background(), "90d64460d14870c08c81352a05dedd3465940a7c")
+	if err != nil || tok.Sub != "opaque_user" {
+		t.Errorf("unable to authenticate opaque access token: %v", err)
+	}
+	if introspected != 1 {
+		t.Errorf("Only opaque access token must be introspected, but %d requests were made", introspected)
+	}
+}
@@ -0,0 +1,178 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
+	"net/http"
+	"time"
+)
+
+const (
+	// backChannelLogoutEvent is the member of the events claim identifying the logout token.
+	backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	// logoutTokenMaxAge limits how long after the issue the logout token is accepted,
+	// the identifiers of the accepted tokens are kept for the same time to detect replays.
+	logoutTokenMaxAge = 5 * time.Minute
+
+	// logoutTokenJtiPrefix is the storage key prefix of the identifiers of the accepted logout tokens.
+	logoutTokenJtiPrefix = "logout_jti:"
+)
+
+// LogoutToken repeats the structure of the Logout Token claims sent by the authorization server
+// to the back-channel logout URI.
+//
+// See more at:
+// - https://openid.net/specs/openid-connect-backchannel-1_0.html#LogoutToken
+type LogoutToken struct {
+	Iss    string                     `json:"iss"`
+	Sub    string                     `json:"sub,omitempty"`
+	Aud    Audience                   `json:"aud"`
+	Iat    int64                      `json:"iat"`
+	Exp    int64                      `json:"exp,omitempty"`
+	Jti    string                     `json:"jti"`
+	Sid    string                     `json:"sid,omitempty"`
+	Events map[string]json.RawMessage `json:"events"`
+
+	// Nonce keeps the raw nonce claim, which is prohibited in the Logout Token, to detect its presence
+	// regardless of its value.
+	Nonce json.RawMessage `json:"nonce,omitempty"`
+}
+
+// ValidateLogoutToken checks the Logout Token as described in the OpenID Connect Back-Channel Logout 1.0
+// section 2.6 and returns its claims in the event of its validity. The identifier of the accepted token
+// is kept in the storage and the token with the same identifier is rejected with ErrTokenReplayed.
+func (j *JwtVerifier) ValidateLogoutToken(ctx context.Context, token string) (*LogoutToken, error) {
+	verified, _, err := j.verifySignature(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	t := &LogoutToken{}
+	if err := json.Unmarshal(verified, t); err != nil {
+		return nil, err
+	}
+
+	if t.Iss != j.config.Issuer {
+		return nil, validationError("iss", ErrInvalidIssuer)
+	}
+	if !t.Aud.Contains(j.config.ClientID) {
+		return nil, validationError("aud", ErrInvalidAudience)
+	}
+
+	now := j.now()
+	if t.Iat == 0 {
+		return nil, validationError("iat", ErrMissingClaim)
+	}
+	iat := time.Unix(t.Iat, 0)
+	if iat.After(now.Add(j.config.Leeway)) {
+		return nil, validationError("iat", ErrTokenUsedBeforeIssued)
+	}
+	if now.After(iat.Add(logoutTokenMaxAge + j.config.Leeway)) {
+		return nil, validationError("iat", ErrTokenExpired)
+	}
+	if t.Exp != 0 && now.After(time.Unix(t.Exp, 0).Add(j.config.Leeway)) {
+		return nil, validationError("exp", ErrTokenExpired)
+	}
+
+	if _, ok := t.Events[backChannelLogoutEvent]; !ok {
+		return nil, validationError("events", ErrMissingClaim)
+	}
+	if len(t.Nonce) > 0 {
+		return nil, validationError("nonce", ErrInvalidNonce)
+	}
+	if t.Sub == "" && t.Sid == "" {
+		return nil, validationError("sid", ErrMissingClaim)
+	}
+	if t.Jti == "" {
+		return nil, validationError("jti", ErrMissingClaim)
+	}
+
+	key := logoutTokenJtiPrefix + t.Jti
+	if i, _ := j.storage.Get(key); i != nil {
+		return nil, validationError("jti", ErrTokenReplayed)
+	}
+	if err := j.storage.Set(key, iat.Add(logoutTokenMaxAge+j.config.Leeway).Unix(), []byte(t.Iss)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// BackChannelLogoutHandler returns the handler of the back-channel logout URI registered for the client.
+// It validates the Logout Token and removes the cached introspections of the tokens issued to
+// the logged out session and subject, so they are checked with the authorization server again.
+//
+// The storage must implement the storage.Indexer interface, the memory and redis adapters do.
+func (j *JwtVerifier) BackChannelLogoutHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.PostFormValue("logout_token")
+		if token == "" {
+			writeLogoutError(w, errors.New("logout_token is missing"))
+			return
+		}
+		t, err := j.ValidateLogoutToken(r.Context(), token)
+		if err != nil {
+			writeLogoutError(w, err)
+			return
+		}
+		if err := j.purgeSession(t.Sub, t.Sid); err != nil {
+			writeLogoutError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// indexIntrospection adds the cached introspection to the subject and session indexes of the storage.
+func (j *JwtVerifier) indexIntrospection(token string, introspect *IntrospectToken) {
+	indexer, ok := j.storage.(storage.Indexer)
+	if !ok {
+		return
+	}
+	if introspect.Sub != "" {
+		_ = indexer.Index("sub:"+introspect.Sub, cacheKey(token), introspect.Exp)
+	}
+	if sid, ok := introspect.Ext["sid"].(string); ok && sid != "" {
+		_ = indexer.Index("sid:"+sid, cacheKey(token), introspect.Exp)
+	}
+}
+
+// purgeSession removes the cached introspections of the session and of the subject. The introspections
+// don't always contain the session identifier, so the subject index is purged too; the introspections
+// of the other sessions of the subject are just requested again.
+func (j *JwtVerifier) purgeSession(sub string, sid string) error {
+	indexer, ok := j.storage.(storage.Indexer)
+	if !ok {
+		return errors.New("jwtverifier: storage doesn't support the lookup by subject")
+	}
+	if sid != "" {
+		if err := indexer.DeleteIndex("sid:" + sid); err != nil {
+			return err
+		}
+	}
+	if sub != "" {
+		if err := indexer.DeleteIndex("sub:" + sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLogoutError responds with the 400 Bad Request as required by the Back-Channel Logout.
+func writeLogoutError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             "invalid_request",
+		"error_description": err.Error(),
+	})
+}
@@ -0,0 +1,121 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logoutTokenClaims(issuer string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    issuer,
+		"sub":    "user_id",
+		"aud":    "CLIENT_ID",
+		"iat":    time.Now().Unix(),
+		"jti":    "logout_jti",
+		"sid":    "session_id",
+		"events": map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}},
+	}
+}
+
+func TestValidateLogoutToken_InvalidClaims(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	for _, tc := range []struct {
+		claim string
+		value interface{}
+		err   error
+	}{
+		{claim: "iss", value: "http://another.issuer", err: ErrInvalidIssuer},
+		{claim: "aud", value: "CLIENT_ID2", err: ErrInvalidAudience},
+		{claim: "iat", value: nil, err: ErrMissingClaim},
+		{claim: "iat", value: time.Now().Add(-time.Hour).Unix(), err: ErrTokenExpired},
+		{claim: "events", value: nil, err: ErrMissingClaim},
+		{claim: "nonce", value: "mynonce", err: ErrInvalidNonce},
+		{claim: "nonce", value: "", err: ErrInvalidNonce},
+		{claim: "jti", value: nil, err: ErrMissingClaim},
+	} {
+		claims := logoutTokenClaims(ts.URL)
+		if tc.value == nil {
+			delete(claims, tc.claim)
+		} else {
+			claims[tc.claim] = tc.value
+		}
+
+		_, err := jwt.ValidateLogoutToken(context.Background(), signer.sign(t, claims))
+		var ve *ValidationError
+		if !errors.Is(err, tc.err) || !errors.As(err, &ve) || ve.Claim != tc.claim {
+			t.Errorf("Invalid error for %s claim [%v], must be [%s]", tc.claim, err, tc.err)
+		}
+	}
+
+	claims := logoutTokenClaims(ts.URL)
+	delete(claims, "sub")
+	delete(claims, "sid")
+	if _, err := jwt.ValidateLogoutToken(context.Background(), signer.sign(t, claims)); !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("Invalid error for token without sub and sid [%v]", err)
+	}
+}
+
+func TestValidateLogoutToken_Replay(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	token := signer.sign(t, logoutTokenClaims(ts.URL))
+	if _, err := jwt.ValidateLogoutToken(context.Background(), token); err != nil {
+		t.Fatalf("unable to validate logout token: %s", err)
+	}
+	if _, err := jwt.ValidateLogoutToken(context.Background(), token); !errors.Is(err, ErrTokenReplayed) {
+		t.Errorf("Invalid error for replayed token [%v], must be [%s]", err, ErrTokenReplayed)
+	}
+}
+
+func TestBackChannelLogoutHandler(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	exp := time.Now().Add(time.Hour).Unix()
+	for token, introspect := range map[string]*IntrospectToken{
+		"session_token": {Active: true, Sub: "another_user", Exp: exp, Ext: map[string]interface{}{"sid": "session_id"}},
+		"user_token":    {Active: true, Sub: "user_id", Exp: exp},
+		"another_token": {Active: true, Sub: "another_user", Exp: exp},
+	} {
+		jwt.storage.Set(cacheKey(token), exp, []byte(`{"active": true}`))
+		jwt.indexIntrospection(token, introspect)
+	}
+
+	h := jwt.BackChannelLogoutHandler()
+	post := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"logout_token": {token}}
+		r := httptest.NewRequest("POST", "/backchannel_logout", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post(signer.sign(t, logoutTokenClaims(ts.URL))); w.Code != http.StatusOK {
+		t.Fatalf("Invalid status code %d: %s", w.Code, w.Body.String())
+	}
+	for token, removed := range map[string]bool{"session_token": true, "user_token": true, "another_token": false} {
+		if i, _ := jwt.storage.Get(cacheKey(token)); (i == nil) != removed {
+			t.Errorf("Invalid storage state of %s, removed must be %t", token, removed)
+		}
+	}
+
+	if w := post("invalid"); w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid status code %d for invalid token", w.Code)
+	}
+}
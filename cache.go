@@ -0,0 +1,158 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/internal"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultNegativeCacheTTL is used to cache the inactive tokens and the tokens of another client
+	// if the Config.NegativeCacheTTL is zero.
+	DefaultNegativeCacheTTL = 10 * time.Second
+
+	// revalidateTimeout limits the duration of the background revalidation of the cached introspection.
+	revalidateTimeout = 30 * time.Second
+
+	// cacheKeyPrefix versions the storage keys of the cached introspections. The cacheEntry would be decoded
+	// as an empty introspection by the earlier releases sharing the storage, so they must not see each other's keys.
+	cacheKeyPrefix = "v2:"
+)
+
+// CachePolicy defines how the cached introspections of the active tokens are revalidated.
+// The zero value keeps the cached introspection until the token expires.
+type CachePolicy struct {
+	// SoftTTL is the time after which the cached introspection is revalidated with the authorization server.
+	// The introspection isn't revalidated if it's zero.
+	SoftTTL time.Duration
+
+	// StaleWhileRevalidate allows to return the cached introspection after the SoftTTL and revalidate it
+	// in the background instead of waiting for the authorization server.
+	StaleWhileRevalidate bool
+
+	// StaleIfError allows to return the cached introspection after the SoftTTL, until the token expires,
+	// if the authorization server responds with 5xx status or doesn't respond in time.
+	StaleIfError bool
+}
+
+const (
+	// negativeInactive marks the cached introspection of the inactive token.
+	negativeInactive = "inactive"
+
+	// negativeAnotherClient marks the cached introspection of the token owned by another client.
+	negativeAnotherClient = "another_client"
+
+	// negativeAnotherAudience marks the cached introspection of the token issued for another audience.
+	negativeAnotherAudience = "another_audience"
+)
+
+// cacheEntry is the introspection result kept in the storage. It contains either the introspection
+// of the active token or the marker of the rejected token.
+type cacheEntry struct {
+	Token     *IntrospectToken `json:"token,omitempty"`
+	Negative  string           `json:"negative,omitempty"`
+	FetchedAt int64            `json:"fetched_at,omitempty"`
+}
+
+// cacheKey returns the storage key of the cached introspection of the token.
+func cacheKey(token string) string {
+	return cacheKeyPrefix + token
+}
+
+// fresh checks that the cached introspection doesn't need the revalidation.
+func (e *cacheEntry) fresh(policy CachePolicy, now time.Time) bool {
+	return policy.SoftTTL <= 0 || now.Before(time.Unix(e.FetchedAt, 0).Add(policy.SoftTTL))
+}
+
+// loadIntrospection returns the cached introspection of the active token or the error the token was
+// rejected with. Both are nil if the token isn't cached.
+func (j *JwtVerifier) loadIntrospection(token string) (*cacheEntry, error) {
+	i, _ := j.storage.Get(cacheKey(token))
+	if i == nil {
+		return nil, nil
+	}
+	e := &cacheEntry{}
+	if err := json.Unmarshal(i, e); err != nil {
+		return nil, err
+	}
+	switch e.Negative {
+	case negativeInactive:
+		return nil, ErrTokenInactive
+	case negativeAnotherClient:
+		return nil, ErrInvalidClient
+	case negativeAnotherAudience:
+		return nil, ErrInvalidAudience
+	}
+	if e.Token == nil {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// storeIntrospection caches the introspection of the active token until the token expires.
+func (j *JwtVerifier) storeIntrospection(token string, introspect *IntrospectToken) error {
+	i, err := json.Marshal(&cacheEntry{Token: introspect, FetchedAt: j.now().Unix()})
+	if err != nil {
+		return err
+	}
+	if err := j.storage.Set(cacheKey(token), introspect.Exp, i); err != nil {
+		return err
+	}
+	j.indexIntrospection(token, introspect)
+	return nil
+}
+
+// storeNegative caches the reason the token was rejected for the Config.NegativeCacheTTL,
+// so the repeated requests with the same token don't reach the authorization server.
+func (j *JwtVerifier) storeNegative(token string, reason string) {
+	ttl := j.config.NegativeCacheTTL
+	if ttl < 0 {
+		return
+	}
+	if ttl == 0 {
+		ttl = DefaultNegativeCacheTTL
+	}
+	if i, err := json.Marshal(&cacheEntry{Negative: reason}); err == nil {
+		if err := j.storage.Set(cacheKey(token), j.now().Add(ttl).Unix(), i); err != nil {
+			j.logf("jwtverifier: cannot cache rejected token: %v", err)
+		}
+	}
+}
+
+// revalidateInBackground introspects the token again without blocking the caller. The revalidation
+// outlives the request that triggered it and shares the request with the concurrent introspections.
+func (j *JwtVerifier) revalidateInBackground(ctx context.Context, token string) {
+	bg := internal.Detach(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(bg, revalidateTimeout)
+		defer cancel()
+		if _, err := j.introspect(ctx, token); err != nil && !errors.Is(err, ErrTokenInactive) && !errors.Is(err, ErrInvalidClient) {
+			j.logf("jwtverifier: cannot revalidate cached introspection: %v", err)
+		}
+	}()
+}
+
+// isUnavailable checks that the error is caused by the unavailability of the authorization server:
+// the 5xx response status, a timeout, a failed connection or the open circuit breaker.
+func isUnavailable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var re *RetrieveError
+	if errors.As(err, &re) {
+		return re.Response != nil && re.Response.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,278 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntrospect_NegativeCache(t *testing.T) {
+	for _, tc := range []struct {
+		response string
+		err      error
+	}{
+		{`{"active":false}`, ErrTokenInactive},
+		{`{"active":true,"client_id":"CLIENT_ID2","exp":4102444800}`, ErrInvalidClient},
+	} {
+		var requests int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Write([]byte(tc.response))
+		}))
+
+		jwt := createJwtVerifier(ts.URL)
+		for i := 0; i < 3; i++ {
+			if _, err := jwt.Introspect(context.Background(), "token1"); !errors.Is(err, tc.err) {
+				t.Errorf("Invalid error [%v], must be [%s]", err, tc.err)
+			}
+		}
+		if n := atomic.LoadInt32(&requests); n != 1 {
+			t.Errorf("Rejected token must be introspected once, but it was introspected %d times", n)
+		}
+		ts.Close()
+	}
+}
+
+func TestIntrospect_VersionedKeys(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"active":false}`))
+	}))
+	defer ts.Close()
+
+	// The introspection cached by the earlier release under the bare token key.
+	legacy := []byte(`{"active":true,"client_id":"CLIENT_ID","sub":"user_id","exp":4102444800}`)
+	jwt := createJwtVerifier(ts.URL)
+	jwt.storage.Set("token1", 4102444800, legacy)
+
+	if _, err := jwt.Introspect(context.Background(), "token1"); !errors.Is(err, ErrTokenInactive) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, ErrTokenInactive)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Entry of the earlier release must be ignored, but %d requests were sent", n)
+	}
+	if i, _ := jwt.storage.Get("token1"); string(i) != string(legacy) {
+		t.Errorf("Entry of the earlier release must be kept, got %s", i)
+	}
+	if i, _ := jwt.storage.Get(cacheKey("token1")); i == nil {
+		t.Error("Rejected token must be cached under the versioned key")
+	}
+}
+
+func TestIntrospect_NegativeCacheDisabled(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"active":false}`))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, NegativeCacheTTL: -1})
+	for i := 0; i < 2; i++ {
+		jwt.Introspect(context.Background(), "token1")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Rejected token must be introspected every time, but it was introspected %d times", n)
+	}
+}
+
+func TestIntrospect_PositiveCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, `{"active":true,"client_id":"CLIENT_ID","sub":"user_id","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	for i := 0; i < 3; i++ {
+		tok, err := jwt.Introspect(context.Background(), "token1")
+		if err != nil || tok.Sub != "user_id" {
+			t.Fatalf("unable to introspect token: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Active token must be introspected once, but it was introspected %d times", n)
+	}
+}
+
+func TestIntrospect_Coalesce(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprintf(w, `{"active":true,"client_id":"CLIENT_ID","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+				t.Errorf("unable to introspect token: %s", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Token must be introspected once, but it was introspected %d times", n)
+	}
+}
+
+func TestIntrospect_CoalesceHangingServer(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	jwt := createJwtVerifier(ts.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	errs := make(chan error, 2)
+	go func() {
+		_, err := jwt.Introspect(ctx, "token1")
+		errs <- err
+	}()
+	<-started
+	// The caller without the deadline joins the hung request, which is bounded by the deadline of the first caller.
+	go func() {
+		_, err := jwt.Introspect(context.Background(), "token1")
+		errs <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Invalid error [%v], must be [%s]", err, context.DeadlineExceeded)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Introspection of the hung server must be bounded")
+		}
+	}
+}
+
+func TestIntrospect_StaleWhileRevalidate(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(w, `{"active":true,"client_id":"CLIENT_ID","sub":"user%d","exp":%d}`, n, time.Now().Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:    "CLIENT_ID",
+		Issuer:      ts.URL,
+		CachePolicy: CachePolicy{SoftTTL: time.Nanosecond, StaleWhileRevalidate: true},
+	})
+	tok, err := jwt.Introspect(context.Background(), "token1")
+	if err != nil || tok.Stale || tok.Sub != "user1" {
+		t.Fatalf("Invalid fresh introspection [%+v] [%v]", tok, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	tok, err = jwt.Introspect(context.Background(), "token1")
+	if err != nil || !tok.Stale || tok.Sub != "user1" {
+		t.Fatalf("Invalid stale introspection [%+v] [%v]", tok, err)
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&requests) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Stale introspection must be revalidated in the background, requests: %d", n)
+	}
+}
+
+func TestIntrospect_StaleIfError(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"active":true,"client_id":"CLIENT_ID","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		staleIfError bool
+		stale        bool
+	}{
+		{staleIfError: true, stale: true},
+		{staleIfError: false, stale: false},
+	} {
+		atomic.StoreInt32(&requests, 0)
+		jwt := NewJwtVerifier(Config{
+			ClientID:    "CLIENT_ID",
+			Issuer:      ts.URL,
+			CachePolicy: CachePolicy{SoftTTL: time.Nanosecond, StaleIfError: tc.staleIfError},
+		})
+		if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+			t.Fatalf("unable to introspect token: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		tok, err := jwt.Introspect(context.Background(), "token1")
+		if tc.stale && (err != nil || !tok.Stale) {
+			t.Errorf("Cached introspection must be served on the server error [%+v] [%v]", tok, err)
+		}
+		if !tc.stale && err == nil {
+			t.Error("Server error must be returned without the StaleIfError")
+		}
+	}
+}
+
+func TestIntrospect_StaleIfErrorCircuitOpen(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"active":true,"client_id":"CLIENT_ID","exp":%d}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:    "CLIENT_ID",
+		Issuer:      ts.URL,
+		CachePolicy: CachePolicy{SoftTTL: time.Nanosecond, StaleIfError: true},
+		Breaker:     BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Hour},
+	})
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Fatalf("unable to introspect token: %s", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// The first failure opens the circuit, the next revalidation is rejected by the open circuit.
+	for i := 0; i < 2; i++ {
+		tok, err := jwt.Introspect(context.Background(), "token1")
+		if err != nil || !tok.Stale {
+			t.Errorf("Cached introspection must be served while the server is unavailable [%+v] [%v]", tok, err)
+		}
+	}
+	if _, err := jwt.Introspect(context.Background(), "token2"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Invalid error of the open circuit [%v], must be [%s]", err, ErrCircuitOpen)
+	}
+}
@@ -0,0 +1,173 @@
+package jwtverifier
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// claimTag is the struct field tag marking the custom claims which must be present, for example:
+//
+//	type Claims struct {
+//		TenantID string `json:"tenant_id" claim:"required"`
+//	}
+const claimTag = "claim"
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// DecodeExt decodes the extra claims of the token into the struct pointed to by v with the encoding/json rules.
+// It works the same for the introspections fetched from the authorization server, verified locally or
+// served from the storage cache.
+//
+// The fields tagged with `claim:"required"` must be present and not null. A missing or wrongly typed
+// claim is returned as *ValidationError with the path of the claim, like "ext.address.city", wrapping
+// ErrMissingClaim or ErrInvalidClaimType.
+func (t *IntrospectToken) DecodeExt(v interface{}) error {
+	payload, err := json.Marshal(t.Ext)
+	if err != nil {
+		return err
+	}
+	return decodeClaims("ext", payload, v)
+}
+
+// Claims decodes the verified payload of the ID Token, including the claims missing in the IdToken
+// fields, into the struct pointed to by v. The required claims are validated as by DecodeExt.
+func (t *IdToken) Claims(v interface{}) error {
+	if t.payload == nil {
+		return errors.New("jwtverifier: id token payload is unavailable, the token must be validated by ValidateIdToken")
+	}
+	return decodeClaims("", t.payload, v)
+}
+
+func decodeClaims(path string, payload []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("jwtverifier: cannot decode claims into %T, a non-nil pointer is expected", v)
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			return validationError(claimPath(path, te.Field), ErrInvalidClaimType)
+		}
+		return err
+	}
+
+	var claims interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+	return checkRequiredClaims(path, claims, rv.Type())
+}
+
+// checkRequiredClaims walks the struct fields along with the decoded claims and checks that
+// the required claims are present.
+func checkRequiredClaims(path string, claims interface{}, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		items, _ := claims.([]interface{})
+		for i, item := range items {
+			if err := checkRequiredClaims(fmt.Sprintf("%s[%d]", path, i), item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, _ := claims.(map[string]interface{})
+		for k, item := range m {
+			if err := checkRequiredClaims(claimPath(path, k), item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		m, _ := claims.(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := claimName(f)
+			if !ok {
+				continue
+			}
+			if name == "" {
+				// The fields of the embedded struct are decoded from the same object.
+				if err := checkRequiredClaims(path, claims, f.Type); err != nil {
+					return err
+				}
+				continue
+			}
+
+			value, found := lookupClaim(m, name)
+			if !found || value == nil {
+				if f.Tag.Get(claimTag) == "required" {
+					return validationError(claimPath(path, name), ErrMissingClaim)
+				}
+				continue
+			}
+			if err := checkRequiredClaims(claimPath(path, name), value, f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// claimName returns the JSON name of the struct field, or the empty name for the embedded struct
+// without the name. It's false for the fields ignored by encoding/json.
+func claimName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name != "" {
+		return name, true
+	}
+	if f.Anonymous {
+		t := f.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", true
+		}
+	}
+	if f.PkgPath != "" {
+		return "", false
+	}
+	return f.Name, true
+}
+
+// lookupClaim finds the claim by the name as encoding/json does, preferring the exact match
+// to the case-insensitive one.
+func lookupClaim(claims map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := claims[name]; ok {
+		return v, true
+	}
+	for k, v := range claims {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func claimPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
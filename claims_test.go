@@ -0,0 +1,110 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type testAddress struct {
+	City    string `json:"city" claim:"required"`
+	Country string `json:"country"`
+}
+
+type testExt struct {
+	TenantID string        `json:"tenant_id" claim:"required"`
+	Roles    []string      `json:"roles"`
+	Level    int           `json:"level"`
+	Address  *testAddress  `json:"address"`
+	Offices  []testAddress `json:"offices"`
+}
+
+func TestDecodeExt(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","exp":4102444800,
+			"ext":{"tenant_id":"t1","roles":["admin"],"level":3,"address":{"city":"Berlin"}}}`))
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	// The second introspection is served from the storage cache.
+	for i := 0; i < 2; i++ {
+		tok, err := jwt.Introspect(context.Background(), "token1")
+		if err != nil {
+			t.Fatalf("unable to introspect token: %s", err)
+		}
+		ext := &testExt{}
+		if err := tok.DecodeExt(ext); err != nil {
+			t.Fatalf("unable to decode extra claims: %s", err)
+		}
+		if ext.TenantID != "t1" || ext.Level != 3 || len(ext.Roles) != 1 || ext.Address == nil || ext.Address.City != "Berlin" {
+			t.Errorf("Unexpected extra claims: %#v", ext)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Token must be introspected once, but %d requests were sent", n)
+	}
+}
+
+func TestDecodeExt_InvalidClaims(t *testing.T) {
+	for _, tc := range []struct {
+		ext   map[string]interface{}
+		claim string
+		err   error
+	}{
+		{ext: nil, claim: "ext.tenant_id", err: ErrMissingClaim},
+		{ext: map[string]interface{}{"tenant_id": nil}, claim: "ext.tenant_id", err: ErrMissingClaim},
+		{ext: map[string]interface{}{"tenant_id": 1}, claim: "ext.tenant_id", err: ErrInvalidClaimType},
+		{ext: map[string]interface{}{"tenant_id": "t1", "level": "high"}, claim: "ext.level", err: ErrInvalidClaimType},
+		{ext: map[string]interface{}{"tenant_id": "t1", "address": map[string]interface{}{"country": "DE"}}, claim: "ext.address.city", err: ErrMissingClaim},
+		{ext: map[string]interface{}{"tenant_id": "t1", "address": map[string]interface{}{"city": true}}, claim: "ext.address.city", err: ErrInvalidClaimType},
+		{ext: map[string]interface{}{"tenant_id": "t1", "offices": []interface{}{map[string]interface{}{"city": "Berlin"}, map[string]interface{}{}}}, claim: "ext.offices[1].city", err: ErrMissingClaim},
+	} {
+		err := (&IntrospectToken{Ext: tc.ext}).DecodeExt(&testExt{})
+		var ve *ValidationError
+		if !errors.Is(err, tc.err) || !errors.As(err, &ve) || ve.Claim != tc.claim {
+			t.Errorf("Invalid error for %v [%v], must be [%s] of %s claim", tc.ext, err, tc.err, tc.claim)
+		}
+	}
+}
+
+func TestIdTokenClaims(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	claims := idTokenClaims(ts.URL)
+	claims["tenant_id"] = "t1"
+	claims["address"] = map[string]interface{}{"city": "Berlin"}
+	tok, err := createJwtVerifier(ts.URL).ValidateIdToken(context.Background(), signer.sign(t, claims))
+	if err != nil {
+		t.Fatalf("unable to validate id token: %s", err)
+	}
+
+	custom := &struct {
+		Sub string `json:"sub"`
+		testExt
+	}{}
+	if err := tok.Claims(custom); err != nil {
+		t.Fatalf("unable to decode id token claims: %s", err)
+	}
+	if custom.Sub != "user_id" || custom.TenantID != "t1" || custom.Address.City != "Berlin" {
+		t.Errorf("Unexpected id token claims: %#v", custom)
+	}
+
+	missing := &struct {
+		Locale string `json:"locale" claim:"required"`
+	}{}
+	var ve *ValidationError
+	if err := tok.Claims(missing); !errors.As(err, &ve) || ve.Claim != "locale" || !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("Invalid error for missing claim [%v]", err)
+	}
+	if err := (&IdToken{}).Claims(custom); err == nil {
+		t.Error("Claims of unvalidated id token must fail")
+	}
+}
@@ -0,0 +1,154 @@
+package jwtverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client authentication methods used for the requests to the token, introspection and revocation endpoints.
+//
+// See more at:
+// - https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
+const (
+	// ClientSecretBasic sends the client credentials with the HTTP Basic authentication scheme.
+	ClientSecretBasic = "client_secret_basic"
+
+	// ClientSecretPost sends the client credentials in the request body.
+	ClientSecretPost = "client_secret_post"
+
+	// PrivateKeyJwt sends the JWT assertion signed with the private key of the client.
+	PrivateKeyJwt = "private_key_jwt"
+
+	// ClientAuthNone sends the client identifier only, it's used by the public clients.
+	ClientAuthNone = "none"
+)
+
+const (
+	// clientAssertionType is the type of the client assertion used by the private_key_jwt method.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// clientAssertionLifetime limits the lifetime of the client assertion.
+	clientAssertionLifetime = time.Minute
+)
+
+// clientAssertion repeats the structure of the client assertion claims.
+type clientAssertion struct {
+	Iss string `json:"iss"`
+	Sub string `json:"sub"`
+	Aud string `json:"aud"`
+	Jti string `json:"jti"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+}
+
+// clientAuthMethod returns the configured client authentication method or the default one:
+// client_secret_basic for the confidential clients and none for the public clients.
+func (j *JwtVerifier) clientAuthMethod() string {
+	if j.config.ClientAuthMethod != "" {
+		return j.config.ClientAuthMethod
+	}
+	if j.config.ClientSecret == "" {
+		return ClientAuthNone
+	}
+	return ClientSecretBasic
+}
+
+// newFormRequest creates the POST request of the form to the endpoint of the authorization server
+// authenticated with the configured client authentication method.
+func (j *JwtVerifier) newFormRequest(endpointURL string, form url.Values) (*http.Request, error) {
+	v := url.Values{}
+	for k, val := range form {
+		v[k] = val
+	}
+
+	method := j.clientAuthMethod()
+	switch method {
+	case ClientSecretBasic:
+	case ClientSecretPost:
+		v.Set("client_id", j.config.ClientID)
+		v.Set("client_secret", j.config.ClientSecret)
+	case PrivateKeyJwt:
+		assertion, err := j.signClientAssertion()
+		if err != nil {
+			return nil, err
+		}
+		v.Set("client_id", j.config.ClientID)
+		v.Set("client_assertion_type", clientAssertionType)
+		v.Set("client_assertion", assertion)
+	case ClientAuthNone:
+		v.Set("client_id", j.config.ClientID)
+	default:
+		return nil, fmt.Errorf("jwtverifier: unsupported client authentication method %q", method)
+	}
+
+	req, err := http.NewRequest("POST", endpointURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if method == ClientSecretBasic {
+		req.SetBasicAuth(url.QueryEscape(j.config.ClientID), url.QueryEscape(j.config.ClientSecret))
+	}
+	return req, nil
+}
+
+// signClientAssertion creates the short-living JWT assertion signed with the private key of the client.
+// The audience of the assertion is the token endpoint as recommended by OpenID Connect.
+func (j *JwtVerifier) signClientAssertion() (string, error) {
+	if j.config.PrivateKey == nil {
+		return "", errors.New("jwtverifier: private key is required for the private_key_jwt client authentication")
+	}
+
+	alg := jwa.SignatureAlgorithm(j.config.PrivateKeyAlgorithm)
+	if alg == "" {
+		switch j.config.PrivateKey.(type) {
+		case *rsa.PrivateKey:
+			alg = jwa.RS256
+		case *ecdsa.PrivateKey:
+			alg = jwa.ES256
+		default:
+			return "", fmt.Errorf("jwtverifier: unsupported private key type %T", j.config.PrivateKey)
+		}
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := j.now()
+	payload, err := json.Marshal(&clientAssertion{
+		Iss: j.config.ClientID,
+		Sub: j.config.ClientID,
+		Aud: j.config.endpoint.tokenURL,
+		Jti: base64.RawURLEncoding.EncodeToString(jti),
+		Iat: now.Unix(),
+		Exp: now.Add(clientAssertionLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	h := &jws.StandardHeaders{}
+	if j.config.PrivateKeyID != "" {
+		if err := h.Set(jws.KeyIDKey, j.config.PrivateKeyID); err != nil {
+			return "", err
+		}
+	}
+	signed, err := jws.Sign(payload, alg, j.config.PrivateKey, jws.WithHeaders(h))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
@@ -0,0 +1,133 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func newClientAuthServer(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("unable to parse form: %s", err)
+		}
+		check(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "90d64460d14870c08c81352a05dedd3465940a7c", "token_type": "bearer", "expires_in": 3600, "active": true, "client_id": "CLIENT_ID"}`))
+	}))
+}
+
+func TestClientAuth_SecretPost(t *testing.T) {
+	ts := newClientAuthServer(t, func(r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Errorf("Unexpected Authorization header [%s]", r.Header.Get("Authorization"))
+		}
+		if r.PostForm.Get("client_id") != "CLIENT_ID" || r.PostForm.Get("client_secret") != "CLIENT_SECRET" {
+			t.Errorf("Invalid client credentials in form: %v", r.PostForm)
+		}
+	})
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:         "CLIENT_ID",
+		ClientSecret:     "CLIENT_SECRET",
+		ClientAuthMethod: ClientSecretPost,
+		Issuer:           ts.URL,
+	})
+	if _, err := jwt.Exchange(context.Background(), "exchange-code"); err != nil {
+		t.Errorf("unable to exchange code: %s", err)
+	}
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Errorf("unable to introspect token: %s", err)
+	}
+	if err := jwt.Revoke(context.Background(), "token1"); err != nil {
+		t.Errorf("unable to revoke token: %s", err)
+	}
+}
+
+func TestClientAuth_None(t *testing.T) {
+	ts := newClientAuthServer(t, func(r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Errorf("Unexpected Authorization header [%s]", r.Header.Get("Authorization"))
+		}
+		if r.PostForm.Get("client_id") != "CLIENT_ID" || r.PostForm.Get("client_secret") != "" {
+			t.Errorf("Invalid client credentials in form: %v", r.PostForm)
+		}
+	})
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL})
+	if _, err := jwt.Refresh(context.Background(), "refresh-token"); err != nil {
+		t.Errorf("unable to refresh token: %s", err)
+	}
+}
+
+func TestClientAuth_IntrospectBasic(t *testing.T) {
+	ts := newClientAuthServer(t, func(r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "CLIENT_ID" || secret != "CLIENT_SECRET" {
+			t.Errorf("Invalid Authorization header [%s]", r.Header.Get("Authorization"))
+		}
+		if r.PostForm.Get("secret") != "" || r.PostForm.Get("client_secret") != "" {
+			t.Errorf("Unexpected client secret in form: %v", r.PostForm)
+		}
+	})
+	defer ts.Close()
+
+	if _, err := createJwtVerifier(ts.URL).Introspect(context.Background(), "token1"); err != nil {
+		t.Errorf("unable to introspect token: %s", err)
+	}
+}
+
+func TestClientAuth_PrivateKeyJwt(t *testing.T) {
+	signer := newTestSigner(t, "client-key")
+	var ts *httptest.Server
+	ts = newClientAuthServer(t, func(r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Errorf("Unexpected Authorization header [%s]", r.Header.Get("Authorization"))
+		}
+		if r.PostForm.Get("client_assertion_type") != clientAssertionType {
+			t.Errorf("Invalid client assertion type [%s]", r.PostForm.Get("client_assertion_type"))
+		}
+
+		assertion := r.PostForm.Get("client_assertion")
+		payload, err := jws.Verify([]byte(assertion), jwa.RS256, &signer.key.PublicKey)
+		if err != nil {
+			t.Fatalf("unable to verify client assertion: %s", err)
+		}
+		if h, err := parseTokenHeader(assertion); err != nil || h.Kid != "client-key" {
+			t.Errorf("Invalid client assertion header: %#v", h)
+		}
+		c := &clientAssertion{}
+		if err := json.Unmarshal(payload, c); err != nil {
+			t.Fatalf("unable to decode client assertion: %s", err)
+		}
+		if c.Iss != "CLIENT_ID" || c.Sub != "CLIENT_ID" || c.Aud != ts.URL+"/oauth2/token" || c.Jti == "" || c.Exp <= c.Iat {
+			t.Errorf("Invalid client assertion claims: %#v", c)
+		}
+	})
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:         "CLIENT_ID",
+		ClientAuthMethod: PrivateKeyJwt,
+		PrivateKey:       signer.key,
+		PrivateKeyID:     "client-key",
+		Issuer:           ts.URL,
+	})
+	if _, err := jwt.ClientCredentialsToken(context.Background()); err != nil {
+		t.Errorf("unable to get client credentials token: %s", err)
+	}
+}
+
+func TestClientAuth_PrivateKeyJwtWithoutKey(t *testing.T) {
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", ClientAuthMethod: PrivateKeyJwt, Issuer: "http://localhost"})
+	if _, err := jwt.newFormRequest("http://localhost/oauth2/token", url.Values{}); err == nil {
+		t.Error("private_key_jwt without private key should have caused an error")
+	}
+}
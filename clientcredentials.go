@@ -0,0 +1,73 @@
+package jwtverifier
+
+import (
+	"context"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clientCredentialsExpiryDelta determines how earlier the cached client credentials token
+// is considered expired than its actual expiration time.
+const clientCredentialsExpiryDelta = 30 * time.Second
+
+// clientCredentialsCache keeps the token obtained with the client credentials grant.
+type clientCredentialsCache struct {
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// ClientCredentialsToken returns the token of the application itself obtained with the client
+// credentials grant, using the ClientID, Scopes and the client authentication method from the configuration.
+// The token is cached in memory and requested again shortly before it expires.
+func (j *JwtVerifier) ClientCredentialsToken(ctx context.Context) (*Token, error) {
+	j.clientCredentials.mu.Lock()
+	defer j.clientCredentials.mu.Unlock()
+
+	if t := j.clientCredentials.token; t != nil && (t.Expiry.IsZero() || j.now().Add(clientCredentialsExpiryDelta).Before(t.Expiry)) {
+		return &Token{Token: t}, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(j.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(j.config.Scopes, " "))
+	}
+	t, err := j.retrieveToken(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	j.clientCredentials.token = t.Token
+	return t, nil
+}
+
+// ClientCredentialsClient returns an HTTP client which authorizes the requests with the client
+// credentials token, see ClientCredentialsToken. The client is used for service-to-service calls.
+func (j *JwtVerifier) ClientCredentialsClient(ctx context.Context) *http.Client {
+	base := j.client(ctx)
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: clientCredentialsSource{ctx: ctx, j: j},
+			Base:   base.Transport,
+		},
+		CheckRedirect: base.CheckRedirect,
+		Jar:           base.Jar,
+		Timeout:       base.Timeout,
+	}
+}
+
+// clientCredentialsSource is a TokenSource returning the cached client credentials token.
+type clientCredentialsSource struct {
+	ctx context.Context
+	j   *JwtVerifier
+}
+
+func (s clientCredentialsSource) Token() (*oauth2.Token, error) {
+	t, err := s.j.ClientCredentialsToken(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Token, nil
+}
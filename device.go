@@ -0,0 +1,129 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// deviceCodeGrantType is the grant type of the device access token request.
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// defaultDevicePollInterval is used if the device authorization response doesn't define the interval.
+	defaultDevicePollInterval = 5 * time.Second
+
+	// deviceSlowDownInterval is added to the polling interval on every slow_down response.
+	deviceSlowDownInterval = 5 * time.Second
+)
+
+// ErrDeviceCodeExpired is returned if the device code expired before the user completed the authorization.
+var ErrDeviceCodeExpired = errors.New("device code is expired")
+
+// DeviceAuthorization repeats the structure of the device authorization response.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc8628#section-3.2
+type DeviceAuthorization struct {
+	// DeviceCode is the device verification code used to poll the token.
+	DeviceCode string `json:"device_code"`
+
+	// UserCode is the end-user verification code which should be displayed to the user.
+	UserCode string `json:"user_code"`
+
+	// VerificationURI is the end-user verification URI which should be displayed to the user.
+	VerificationURI string `json:"verification_uri"`
+
+	// VerificationURIComplete is the verification URI that includes the user code, it's intended
+	// for non-textual transmission, e.g. QR code.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+
+	// ExpiresIn is the lifetime in seconds of the device code and user code.
+	ExpiresIn int64 `json:"expires_in"`
+
+	// Interval is the minimum amount of time in seconds that the client should wait between polling requests.
+	Interval int64 `json:"interval,omitempty"`
+
+	// Expiry is the time when the device code expires, calculated from ExpiresIn.
+	Expiry time.Time `json:"-"`
+}
+
+// DeviceAuthorize starts the device authorization grant for the clients without a browser or with
+// limited input capabilities. Display the UserCode and VerificationURI to the user, then call
+// PollDeviceToken to wait until the user completes the authorization.
+func (j *JwtVerifier) DeviceAuthorize(ctx context.Context) (*DeviceAuthorization, error) {
+	if err := checkEndpoint("device authorization", j.config.endpoint.deviceAuthURL); err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	if len(j.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(j.config.Scopes, " "))
+	}
+	body, err := j.postForm(ctx, j.config.endpoint.deviceAuthURL, form)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DeviceAuthorization{}
+	if err := json.Unmarshal(body, d); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot decode device authorization: %v", err)
+	}
+	if d.DeviceCode == "" {
+		return nil, errors.New("oauth2: server response missing device_code")
+	}
+	if d.ExpiresIn > 0 {
+		d.Expiry = j.now().Add(time.Duration(d.ExpiresIn) * time.Second)
+	}
+	return d, nil
+}
+
+// PollDeviceToken polls the token endpoint until the user completes the device authorization.
+// The polling interval from the device authorization is honoured and increased on every slow_down
+// response. ErrDeviceCodeExpired is returned if the device code expires and the context error is
+// returned if the context is cancelled.
+func (j *JwtVerifier) PollDeviceToken(ctx context.Context, d *DeviceAuthorization) (*Token, error) {
+	interval := time.Duration(d.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if !d.Expiry.IsZero() && j.now().After(d.Expiry) {
+			return nil, ErrDeviceCodeExpired
+		}
+
+		t, err := j.retrieveToken(ctx, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {d.DeviceCode},
+		})
+		if err == nil {
+			return t, nil
+		}
+
+		var re *RetrieveError
+		if !errors.As(err, &re) {
+			return nil, err
+		}
+		switch re.ErrorCode {
+		case "authorization_pending":
+		case "slow_down":
+			interval += deviceSlowDownInterval
+		case "expired_token":
+			return nil, ErrDeviceCodeExpired
+		default:
+			return nil, err
+		}
+	}
+}
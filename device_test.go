@@ -0,0 +1,90 @@
+package jwtverifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeviceAuthorize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "/oauth2/device/auth" {
+			t.Errorf("Unexpected device authorization request URL %q", r.URL)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "scope=scope" {
+			t.Errorf("Unexpected device authorization payload; got %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code": "device-code", "user_code": "WDJB-MJHT", "verification_uri": "https://auth/device", "expires_in": 1800, "interval": 5}`))
+	}))
+	defer ts.Close()
+
+	d, err := createJwtVerifier(ts.URL).DeviceAuthorize(context.Background())
+	if err != nil {
+		t.Fatalf("unable to start device authorization: %s", err)
+	}
+	if d.DeviceCode != "device-code" || d.UserCode != "WDJB-MJHT" || d.VerificationURI != "https://auth/device" || d.Interval != 5 {
+		t.Errorf("Unexpected device authorization %#v", d)
+	}
+	if d.Expiry.Before(time.Now().Add(29 * time.Minute)) {
+		t.Errorf("Invalid device code expiry %s", d.Expiry)
+	}
+}
+
+func TestPollDeviceToken(t *testing.T) {
+	polls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "device_code=device-code&grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Adevice_code" {
+			t.Errorf("Unexpected device token payload; got %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if polls == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "authorization_pending"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "90d64460d14870c08c81352a05dedd3465940a7c", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	tok, err := createJwtVerifier(ts.URL).PollDeviceToken(context.Background(), &DeviceAuthorization{DeviceCode: "device-code", Interval: 1})
+	if err != nil {
+		t.Fatalf("unable to poll device token: %s", err)
+	}
+	if tok.AccessToken != "90d64460d14870c08c81352a05dedd3465940a7c" || polls != 2 {
+		t.Errorf("Unexpected device token %#v after %d polls", tok.Token, polls)
+	}
+}
+
+func TestPollDeviceToken_Expired(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "expired_token"}`))
+	}))
+	defer ts.Close()
+
+	_, err := createJwtVerifier(ts.URL).PollDeviceToken(context.Background(), &DeviceAuthorization{DeviceCode: "device-code", Interval: 1})
+	if err != ErrDeviceCodeExpired {
+		t.Errorf("Invalid error [%v], must be [%s]", err, ErrDeviceCodeExpired)
+	}
+}
+
+func TestPollDeviceToken_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Token must not be polled after the context is cancelled")
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := createJwtVerifier(ts.URL).PollDeviceToken(ctx, &DeviceAuthorization{DeviceCode: "device-code"})
+	if err != context.Canceled {
+		t.Errorf("Invalid error [%v], must be [%s]", err, context.Canceled)
+	}
+}
@@ -0,0 +1,89 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/context/ctxhttp"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// discoveryPath is the well-known path of the OpenID Connect discovery document relative to the issuer.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// providerMetadata repeats the structure of the OpenID Provider Metadata document.
+//
+// See more at:
+// - https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+// - https://tools.ietf.org/html/rfc8414#section-2
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	IntrospectionEndpoint string `json:"introspection_endpoint"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	DeviceAuthEndpoint    string `json:"device_authorization_endpoint"`
+
+	IdTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// NewJwtVerifierWithDiscovery create new instance of verifier with the endpoint URLs taken from
+// the OpenID Connect discovery document of the issuer. The issuer declared in the document must
+// match the configured Issuer. Non-empty Config.Endpoints values take precedence over the discovered ones.
+func NewJwtVerifierWithDiscovery(ctx context.Context, config Config, options ...interface{}) (*JwtVerifier, error) {
+	j := newJwtVerifier(config, options...)
+	m, err := discover(ctx, j.client(ctx), config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if m.Issuer != config.Issuer {
+		return nil, fmt.Errorf("jwtverifier: issuer did not match the issuer returned by provider, expected %q got %q", config.Issuer, m.Issuer)
+	}
+
+	j.setEndpoint(endpoint{
+		authURL:       m.AuthorizationEndpoint,
+		tokenURL:      m.TokenEndpoint,
+		userInfoURL:   m.UserInfoEndpoint,
+		introspectURL: m.IntrospectionEndpoint,
+		revokeUrl:     m.RevocationEndpoint,
+		logoutUrl:     m.EndSessionEndpoint,
+		jwksUrl:       m.JwksURI,
+		deviceAuthURL: m.DeviceAuthEndpoint,
+	})
+	j.metadata = m
+	return j, nil
+}
+
+func discover(ctx context.Context, client *http.Client, issuer string) (*providerMetadata, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	r, err := ctxhttp.Do(ctx, client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot fetch provider metadata: %v", err)
+	}
+	if code := r.StatusCode; code < 200 || code > 299 {
+		return nil, newRetrieveError(r, body)
+	}
+
+	m := &providerMetadata{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot decode provider metadata: %v", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,90 @@
+package jwtverifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewJwtVerifierWithDiscovery(t *testing.T) {
+	var issuer string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "/.well-known/openid-configuration" {
+			t.Errorf("Unexpected discovery request URL %q", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"issuer": "` + issuer + `",
+			"authorization_endpoint": "` + issuer + `/authorize",
+			"token_endpoint": "` + issuer + `/token",
+			"userinfo_endpoint": "` + issuer + `/userinfo",
+			"introspection_endpoint": "` + issuer + `/introspect",
+			"revocation_endpoint": "` + issuer + `/revoke",
+			"end_session_endpoint": "` + issuer + `/logout",
+			"jwks_uri": "` + issuer + `/keys"
+		}`))
+	}))
+	defer ts.Close()
+	issuer = ts.URL
+
+	jwt, err := NewJwtVerifierWithDiscovery(context.Background(), Config{
+		ClientID:  "CLIENT_ID",
+		Issuer:    ts.URL,
+		Endpoints: Endpoints{IntrospectURL: "http://localhost/custom/introspect"},
+	})
+	if err != nil {
+		t.Fatalf("unable to discover provider: %s", err.Error())
+	}
+
+	e := jwt.config.endpoint
+	for _, tc := range []struct{ got, want string }{
+		{e.authURL, ts.URL + "/authorize"},
+		{e.tokenURL, ts.URL + "/token"},
+		{e.userInfoURL, ts.URL + "/userinfo"},
+		{e.introspectURL, "http://localhost/custom/introspect"},
+		{e.revokeUrl, ts.URL + "/revoke"},
+		{e.logoutUrl, ts.URL + "/logout"},
+		{e.jwksUrl, ts.URL + "/keys"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("Invalid endpoint URL [%s], expected [%s]", tc.got, tc.want)
+		}
+	}
+}
+
+func TestNewJwtVerifierWithDiscovery_IssuerMismatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"issuer": "http://another.issuer"}`))
+	}))
+	defer ts.Close()
+
+	if _, err := NewJwtVerifierWithDiscovery(context.Background(), Config{Issuer: ts.URL}); err == nil {
+		t.Error("discovery with another issuer should have caused an error")
+	}
+}
+
+func TestNewJwtVerifierWithDiscovery_Failed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	if _, err := NewJwtVerifierWithDiscovery(context.Background(), Config{Issuer: ts.URL}); err == nil {
+		t.Error("discovery should have caused an error")
+	}
+}
+
+func TestNewJwtVerifier_EndpointOverrides(t *testing.T) {
+	jwt := NewJwtVerifier(Config{
+		ClientID:  "CLIENT_ID",
+		Issuer:    "http://localhost",
+		Endpoints: Endpoints{AuthURL: "http://localhost/authorize"},
+	})
+	if got := jwt.CreateAuthUrl(""); got != "http://localhost/authorize?client_id=CLIENT_ID&response_type=code" {
+		t.Errorf("Invalid auth URL [%s]", got)
+	}
+	if jwt.config.endpoint.tokenURL != "http://localhost/oauth2/token" {
+		t.Errorf("Invalid token URL [%s]", jwt.config.endpoint.tokenURL)
+	}
+}
@@ -0,0 +1,111 @@
+package jwtverifier
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidSignature is returned if the token signature can't be verified with the keys of the authorization server.
+	ErrInvalidSignature = errors.New("token signature is invalid")
+
+	// ErrUnknownSigningKey is returned if the key the token is signed with isn't found in the key set.
+	ErrUnknownSigningKey = errors.New("token is signed with unknown key")
+
+	// ErrUnsupportedAlgorithm is returned if the token is signed with an algorithm which isn't allowed.
+	ErrUnsupportedAlgorithm = errors.New("token is signed with unsupported algorithm")
+
+	// ErrMissingClaim is returned if the required claim is absent in the token.
+	ErrMissingClaim = errors.New("required claim is missing")
+
+	// ErrInvalidClaimType is returned if the claim can't be decoded into the field of the expected type.
+	ErrInvalidClaimType = errors.New("claim has invalid type")
+
+	// ErrInvalidIssuer is returned if the token is issued by another authorization server.
+	ErrInvalidIssuer = errors.New("token is issued by another issuer")
+
+	// ErrUnknownIssuer is returned by the Registry if there is no verifier for the issuer of the token or request.
+	ErrUnknownIssuer = errors.New("token is issued by unknown issuer")
+
+	// ErrInvalidAudience is returned if none of the expected audiences is listed in the token audiences.
+	ErrInvalidAudience = errors.New("token is issued for another audience")
+
+	// ErrTokenInactive is returned if the authorization server reports the token as inactive.
+	ErrTokenInactive = errors.New("token isn't active")
+
+	// ErrInvalidClient is returned if the token is requested by another client, for example
+	// by Introspect if the introspected token is owned by another client.
+	ErrInvalidClient = errors.New("token is owned by another client")
+
+	// ErrInvalidTokenType is returned if the token type header doesn't match the expected one.
+	ErrInvalidTokenType = errors.New("token type is invalid")
+
+	// ErrInvalidAuthorizedParty is returned if the token is issued to another authorized party.
+	ErrInvalidAuthorizedParty = errors.New("token is issued to another authorized party")
+
+	// ErrTokenExpired is returned if the expiration time of the token has passed.
+	ErrTokenExpired = errors.New("token is expired")
+
+	// ErrTokenUsedBeforeIssued is returned if the token is issued in the future.
+	ErrTokenUsedBeforeIssued = errors.New("token is used before issued")
+
+	// ErrTokenNotValidYet is returned if the token is used before its not before time.
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+
+	// ErrTokenReplayed is returned if the token with the same identifier has already been used.
+	ErrTokenReplayed = errors.New("token is already used")
+
+	// ErrInvalidNonce is returned if the token nonce doesn't match the expected one.
+	ErrInvalidNonce = errors.New("token nonce doesn't match")
+
+	// ErrInvalidAccessTokenHash is returned if the at_hash claim doesn't match the access token.
+	ErrInvalidAccessTokenHash = errors.New("access token hash doesn't match")
+
+	// ErrAuthenticationTooOld is returned if the end-user authentication is older than the allowed maximum age.
+	ErrAuthenticationTooOld = errors.New("authentication is too old")
+)
+
+// ValidationError is returned if the token fails one of the validation checks.
+// The Err contains one of the sentinel errors describing the failed check.
+type ValidationError struct {
+	// Claim is the name of the claim which failed the check, it's empty for the signature checks.
+	Claim string
+
+	// Err describes the failed check.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Claim == "" {
+		return fmt.Sprintf("jwtverifier: %v", e.Err)
+	}
+	return fmt.Sprintf("jwtverifier: invalid %s claim: %v", e.Claim, e.Err)
+}
+
+// Unwrap returns the sentinel error of the failed check.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func validationError(claim string, err error) error {
+	return &ValidationError{Claim: claim, Err: err}
+}
+
+// TransportError is returned if the request to the authorization server fails without the response,
+// for example because of a network error or a timeout.
+type TransportError struct {
+	// Endpoint is the URL of the endpoint of the failed request.
+	Endpoint string
+
+	// Err is the error of the HTTP client.
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("jwtverifier: request to %s failed: %v", e.Endpoint, e.Err)
+}
+
+// Unwrap returns the error of the HTTP client.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,65 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetrieveError_Is(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant", "error_description": "Code is expired"}`))
+	}))
+	defer ts.Close()
+
+	_, err := createJwtVerifier(ts.URL).Exchange(context.Background(), "exchange-code")
+	if !errors.Is(err, &RetrieveError{ErrorCode: "invalid_grant"}) {
+		t.Errorf("Error must match the invalid_grant code [%v]", err)
+	}
+	if errors.Is(err, &RetrieveError{ErrorCode: "invalid_client"}) {
+		t.Errorf("Error must not match another code [%v]", err)
+	}
+	var re *RetrieveError
+	if !errors.As(err, &re) || re.ErrorDescription != "Code is expired" {
+		t.Errorf("Invalid error description [%v]", err)
+	}
+}
+
+func TestTransportError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	_, err := createJwtVerifier(url).Introspect(context.Background(), "token1")
+	var te *TransportError
+	if !errors.As(err, &te) || te.Endpoint != url+"/oauth2/introspect" {
+		t.Fatalf("Invalid transport error [%v]", err)
+	}
+	var oe *net.OpError
+	if !errors.As(err, &oe) {
+		t.Errorf("Transport error must wrap the network error [%v]", err)
+	}
+}
+
+func TestIntrospect_TypedErrors(t *testing.T) {
+	for _, tc := range []struct {
+		response string
+		err      error
+	}{
+		{`{"active":false}`, ErrTokenInactive},
+		{`{"active":true,"client_id":"CLIENT_ID2"}`, ErrInvalidClient},
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(tc.response))
+		}))
+		if _, err := createJwtVerifier(ts.URL).Introspect(context.Background(), "token1"); !errors.Is(err, tc.err) {
+			t.Errorf("Invalid error [%v], must be [%s]", err, tc.err)
+		}
+		ts.Close()
+	}
+}
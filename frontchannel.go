@@ -0,0 +1,48 @@
+package jwtverifier
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// FrontChannelLogoutHandler returns the handler of the front-channel logout URI registered for the client.
+// The authorization server loads the URI in an iframe of its logout page with the iss and sid query
+// parameters. The handler checks the issuer, clears the session cookie with the given name and removes
+// the cached introspections of the session and of the token kept in the cookie.
+//
+// See more at:
+// - https://openid.net/specs/openid-connect-frontchannel-1_0.html#RPLogout
+func (j *JwtVerifier) FrontChannelLogoutHandler(cookieName string) http.Handler {
+	frameAncestors := "'none'"
+	if u, err := url.Parse(j.config.Issuer); err == nil && u.Host != "" {
+		frameAncestors = u.Scheme + "://" + u.Host
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+		w.Header().Set("Content-Security-Policy", "frame-ancestors "+frameAncestors)
+
+		q := r.URL.Query()
+		iss, sid := q.Get("iss"), q.Get("sid")
+		// Both parameters are sent if the client requires the session, otherwise neither of them.
+		if (iss != "" || sid != "") && iss != j.config.Issuer {
+			http.Error(w, ErrInvalidIssuer.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if c, err := r.Cookie(cookieName); err == nil {
+			if c.Value != "" {
+				_ = j.storage.Delete(cacheKey(c.Value))
+			}
+			http.SetCookie(w, &http.Cookie{Name: cookieName, Value: "", Path: "/", MaxAge: -1})
+		}
+		if sid != "" {
+			_ = j.purgeSession("", sid)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,53 @@
+package jwtverifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFrontChannelLogoutHandler(t *testing.T) {
+	jwt := createJwtVerifier("http://localhost:8080")
+	exp := time.Now().Add(time.Hour).Unix()
+	for _, token := range []string{"session_token", "cookie_token"} {
+		jwt.storage.Set(cacheKey(token), exp, []byte(`{"active": true}`))
+	}
+	jwt.indexIntrospection("session_token", &IntrospectToken{Exp: exp, Ext: map[string]interface{}{"sid": "session_id"}})
+
+	q := url.Values{"iss": {"http://localhost:8080"}, "sid": {"session_id"}}
+	r := httptest.NewRequest("GET", "/frontchannel_logout?"+q.Encode(), nil)
+	r.AddCookie(&http.Cookie{Name: "auth", Value: "cookie_token"})
+	w := httptest.NewRecorder()
+	jwt.FrontChannelLogoutHandler("auth").ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Invalid status code %d", w.Code)
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); csp != "frame-ancestors http://localhost:8080" {
+		t.Errorf("Invalid Content-Security-Policy [%s]", csp)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache, no-store, must-revalidate" {
+		t.Errorf("Invalid Cache-Control [%s]", cc)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "auth" || cookies[0].MaxAge >= 0 {
+		t.Errorf("Session cookie must be cleared: %v", cookies)
+	}
+	for _, token := range []string{"session_token", "cookie_token"} {
+		if i, _ := jwt.storage.Get(cacheKey(token)); i != nil {
+			t.Errorf("Token %s must be removed from the storage", token)
+		}
+	}
+}
+
+func TestFrontChannelLogoutHandler_InvalidIssuer(t *testing.T) {
+	jwt := createJwtVerifier("http://localhost:8080")
+	q := url.Values{"iss": {"http://another.issuer"}, "sid": {"session_id"}}
+	w := httptest.NewRecorder()
+	jwt.FrontChannelLogoutHandler("auth").ServeHTTP(w, httptest.NewRequest("GET", "/frontchannel_logout?"+q.Encode(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid status code %d, expected %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/ProtocolONE/authone-jwt-verifier-golang
 
+go 1.13
+
 require (
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-redis/redis v6.15.1+incompatible
@@ -0,0 +1,133 @@
+package jwtverifier
+
+import (
+	"context"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// IdTokenOption contains an additional expectation for the ID Token validation.
+type IdTokenOption func(*idTokenExpectations)
+
+type idTokenExpectations struct {
+	nonce       string
+	accessToken string
+	maxAge      time.Duration
+}
+
+// ExpectNonce requires the nonce claim of the ID Token to match the nonce sent in the authentication request.
+func ExpectNonce(nonce string) IdTokenOption {
+	return func(e *idTokenExpectations) {
+		e.nonce = nonce
+	}
+}
+
+// ExpectAccessToken requires the at_hash claim of the ID Token, if it's present, to match the access token
+// issued together with the ID Token.
+func ExpectAccessToken(accessToken string) IdTokenOption {
+	return func(e *idTokenExpectations) {
+		e.accessToken = accessToken
+	}
+}
+
+// ExpectMaxAge requires the end-user authentication to be not older than the max_age sent in the
+// authentication request.
+func ExpectMaxAge(maxAge time.Duration) IdTokenOption {
+	return func(e *idTokenExpectations) {
+		e.maxAge = maxAge
+	}
+}
+
+// ValidateIdToken used to check the ID Token and returns its claims (as custom json object) in the event of its validity.
+// The signing key is selected by the key identifier from the cached key set of the authorization server.
+//
+// The token is validated as described in the OpenID Connect Core 1.0 section 3.1.3.7. Every failed check
+// is returned as *ValidationError wrapping one of the sentinel errors.
+func (j *JwtVerifier) ValidateIdToken(ctx context.Context, token string, options ...IdTokenOption) (*IdToken, error) {
+	e := &idTokenExpectations{}
+	for _, o := range options {
+		o(e)
+	}
+
+	verified, h, err := j.verifySignature(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	t := &IdToken{}
+	err = json.Unmarshal(verified, t)
+	if err != nil {
+		return nil, err
+	}
+	t.payload = verified
+
+	if t.Iss != j.config.Issuer {
+		return nil, validationError("iss", ErrInvalidIssuer)
+	}
+	if !t.Aud.Contains(j.config.ClientID) {
+		return nil, validationError("aud", ErrInvalidAudience)
+	}
+	if (len(t.Aud) > 1 || t.Azp != "") && t.Azp != j.config.ClientID {
+		return nil, validationError("azp", ErrInvalidAuthorizedParty)
+	}
+
+	now := j.now()
+	if t.Exp == 0 {
+		return nil, validationError("exp", ErrMissingClaim)
+	}
+	if now.After(time.Unix(t.Exp, 0).Add(j.config.Leeway)) {
+		return nil, validationError("exp", ErrTokenExpired)
+	}
+	if t.Nbf != 0 && time.Unix(t.Nbf, 0).After(now.Add(j.config.Leeway)) {
+		return nil, validationError("nbf", ErrTokenNotValidYet)
+	}
+	if t.Iat == 0 {
+		return nil, validationError("iat", ErrMissingClaim)
+	}
+	if time.Unix(int64(t.Iat), 0).After(now.Add(j.config.Leeway)) {
+		return nil, validationError("iat", ErrTokenUsedBeforeIssued)
+	}
+
+	if e.nonce != "" && subtle.ConstantTimeCompare([]byte(t.Nonce), []byte(e.nonce)) != 1 {
+		return nil, validationError("nonce", ErrInvalidNonce)
+	}
+	if e.accessToken != "" && t.AtHash != "" && !checkAccessTokenHash(h.Alg, e.accessToken, t.AtHash) {
+		return nil, validationError("at_hash", ErrInvalidAccessTokenHash)
+	}
+	if e.maxAge > 0 {
+		if t.AuthTime == 0 {
+			return nil, validationError("auth_time", ErrMissingClaim)
+		}
+		if now.After(time.Unix(int64(t.AuthTime), 0).Add(e.maxAge + j.config.Leeway)) {
+			return nil, validationError("auth_time", ErrAuthenticationTooOld)
+		}
+	}
+
+	return t, nil
+}
+
+// checkAccessTokenHash compares the at_hash claim with the left-most half of the access token hash
+// calculated with the hash function of the ID Token signing algorithm.
+func checkAccessTokenHash(alg string, accessToken string, atHash string) bool {
+	var h crypto.Hash
+	switch alg {
+	case "RS256", "ES256", "PS256", "HS256":
+		h = crypto.SHA256
+	case "RS384", "ES384", "PS384", "HS384":
+		h = crypto.SHA384
+	case "RS512", "ES512", "PS512", "HS512":
+		h = crypto.SHA512
+	default:
+		return false
+	}
+
+	hash := h.New()
+	hash.Write([]byte(accessToken))
+	sum := hash.Sum(nil)
+	expected := base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(atHash)) == 1
+}
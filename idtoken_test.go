@@ -0,0 +1,125 @@
+package jwtverifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func idTokenClaims(issuer string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":       issuer,
+		"sub":       "user_id",
+		"aud":       "CLIENT_ID",
+		"exp":       now.Add(time.Hour).Unix(),
+		"iat":       now.Unix(),
+		"auth_time": now.Add(-time.Minute).Unix(),
+		"nonce":     "mynonce",
+		"sid":       "session_id",
+	}
+}
+
+func TestValidateIdToken(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	sum := sha256.Sum256([]byte("access-token"))
+	claims := idTokenClaims(ts.URL)
+	claims["at_hash"] = base64.RawURLEncoding.EncodeToString(sum[:16])
+
+	jwt := createJwtVerifier(ts.URL)
+	tok, err := jwt.ValidateIdToken(context.Background(), signer.sign(t, claims),
+		ExpectNonce("mynonce"), ExpectAccessToken("access-token"), ExpectMaxAge(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to validate id token: %s", err)
+	}
+	if tok.Sub != "user_id" || tok.Sid != "session_id" || !tok.Aud.Contains("CLIENT_ID") {
+		t.Errorf("Unexpected id token claims: %#v", tok)
+	}
+}
+
+func TestValidateIdToken_InvalidClaims(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Leeway: time.Minute})
+	now := time.Now()
+
+	multiple := idTokenClaims(ts.URL)
+	multiple["aud"] = []string{"CLIENT_ID", "CLIENT_ID2"}
+	if _, err := jwt.ValidateIdToken(context.Background(), signer.sign(t, multiple)); !errors.Is(err, ErrInvalidAuthorizedParty) {
+		t.Errorf("Invalid error for multiple audiences without azp [%v]", err)
+	}
+
+	for _, tc := range []struct {
+		claim   string
+		value   interface{}
+		options []IdTokenOption
+		err     error
+	}{
+		{claim: "iss", value: "http://another.issuer", err: ErrInvalidIssuer},
+		{claim: "aud", value: []string{"CLIENT_ID2"}, err: ErrInvalidAudience},
+		{claim: "azp", value: "CLIENT_ID2", err: ErrInvalidAuthorizedParty},
+		{claim: "exp", value: nil, err: ErrMissingClaim},
+		{claim: "exp", value: now.Add(-2 * time.Minute).Unix(), err: ErrTokenExpired},
+		{claim: "nbf", value: now.Add(2 * time.Minute).Unix(), err: ErrTokenNotValidYet},
+		{claim: "iat", value: now.Add(2 * time.Minute).Unix(), err: ErrTokenUsedBeforeIssued},
+		{claim: "nonce", value: "another", options: []IdTokenOption{ExpectNonce("mynonce")}, err: ErrInvalidNonce},
+		{claim: "at_hash", value: "invalid", options: []IdTokenOption{ExpectAccessToken("access-token")}, err: ErrInvalidAccessTokenHash},
+		{claim: "auth_time", value: now.Add(-time.Hour).Unix(), options: []IdTokenOption{ExpectMaxAge(time.Minute)}, err: ErrAuthenticationTooOld},
+		{claim: "auth_time", value: nil, options: []IdTokenOption{ExpectMaxAge(time.Minute)}, err: ErrMissingClaim},
+	} {
+		claims := idTokenClaims(ts.URL)
+		if tc.value == nil {
+			delete(claims, tc.claim)
+		} else {
+			claims[tc.claim] = tc.value
+		}
+
+		_, err := jwt.ValidateIdToken(context.Background(), signer.sign(t, claims), tc.options...)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Invalid error for %s claim [%v], must be [%s]", tc.claim, err, tc.err)
+			continue
+		}
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Claim != tc.claim {
+			t.Errorf("Invalid validation error for %s claim: %#v", tc.claim, err)
+		}
+	}
+}
+
+func TestValidateIdToken_LeewayExpired(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	claims := idTokenClaims(ts.URL)
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	token := signer.sign(t, claims)
+
+	if _, err := createJwtVerifier(ts.URL).ValidateIdToken(context.Background(), token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired token without leeway should have caused an error, got %v", err)
+	}
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Leeway: time.Minute})
+	if _, err := jwt.ValidateIdToken(context.Background(), token); err != nil {
+		t.Errorf("token expired within leeway should be valid: %s", err)
+	}
+}
+
+func TestValidateIdToken_UnsupportedAlgorithm(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, SigningAlgorithms: []string{"ES256"}})
+	_, err := jwt.ValidateIdToken(context.Background(), signer.sign(t, idTokenClaims(ts.URL)))
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, ErrUnsupportedAlgorithm)
+	}
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultFlightTimeout limits the execution shared by the callers if the FlightGroup.Timeout is zero.
+const DefaultFlightTimeout = 30 * time.Second
+
+// FlightGroup coalesces the concurrent calls with the same key into one execution.
+// The callers share the result of the execution, but each of them waits for it
+// with its own context. The execution is canceled when all callers gave up waiting
+// or when its deadline is exceeded.
+type FlightGroup struct {
+	// Timeout limits the execution, so the hung one doesn't block the callers joining it
+	// as long as they keep coming. The DefaultFlightTimeout is used if it's zero.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done    chan struct{}
+	val     interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Do executes the fn for the key unless the execution for the same key is already in flight,
+// and waits for its result. The fn gets the context which keeps the values and the deadline of
+// the context of the first caller, but isn't canceled with it. The deadline is limited by the Timeout.
+func (g *FlightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.waiters++
+	} else {
+		timeout := g.Timeout
+		if timeout <= 0 {
+			timeout = DefaultFlightTimeout
+		}
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		fctx, cancel := context.WithDeadline(Detach(ctx), deadline)
+		c = &flightCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = c
+		go g.run(fctx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// The canceled call must not be joined by the new callers.
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *FlightGroup) run(ctx context.Context, key string, c *flightCall, fn func(ctx context.Context) (interface{}, error)) {
+	c.val, c.err = fn(ctx)
+	c.cancel()
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// Detach returns the context which keeps the values of the parent context,
+// but is neither canceled nor has a deadline.
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
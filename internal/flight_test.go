@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup_Coalesce(t *testing.T) {
+	var g FlightGroup
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+			if err != nil || v != "value" {
+				t.Errorf("Invalid result [%v] [%v]", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Function must be called once, but it was called %d times", n)
+	}
+}
+
+func TestFlightGroup_CallerCancel(t *testing.T) {
+	var g FlightGroup
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.Do(ctx1, "key", fn)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := g.Do(ctx2, "key", fn)
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, context.Canceled)
+	}
+	select {
+	case <-canceled:
+		t.Fatal("Execution must not be canceled while another caller waits")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel2()
+	<-errs
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("Execution must be canceled when all callers gave up")
+	}
+}
+
+func TestFlightGroup_CallAfterCancel(t *testing.T) {
+	var g FlightGroup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := g.Do(ctx, "key", func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-release
+			return nil, ctx.Err()
+		})
+		errs <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Invalid error [%v], must be [%s]", err, context.Canceled)
+	}
+
+	// The canceled execution is still running, but the new caller must not join it.
+	v, err := g.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "value", ctx.Err()
+	})
+	if err != nil || v != "value" {
+		t.Errorf("Invalid result [%v] [%v]", v, err)
+	}
+}
+
+func TestFlightGroup_Deadline(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		first   time.Duration
+	}{
+		{name: "first caller deadline", timeout: time.Hour, first: 50 * time.Millisecond},
+		{name: "group timeout", timeout: 50 * time.Millisecond},
+	} {
+		g := FlightGroup{Timeout: tc.timeout}
+		started := make(chan struct{})
+		fn := func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		ctx := context.Background()
+		if tc.first > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tc.first)
+			defer cancel()
+		}
+		errs := make(chan error, 2)
+		go func() {
+			_, err := g.Do(ctx, "key", fn)
+			errs <- err
+		}()
+		<-started
+		// The caller without the deadline joins the execution and must not wait for it forever.
+		go func() {
+			_, err := g.Do(context.Background(), "key", fn)
+			errs <- err
+		}()
+
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-errs:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("%s: invalid error [%v], must be [%s]", tc.name, err, context.DeadlineExceeded)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: execution must be canceled by its deadline", tc.name)
+			}
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancel()
+
+	ctx := Detach(parent)
+	if ctx.Err() != nil || ctx.Value(key{}) != "value" {
+		t.Errorf("Detached context must keep values without cancellation: %v %v", ctx.Err(), ctx.Value(key{}))
+	}
+}
@@ -0,0 +1,321 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/internal"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"golang.org/x/net/context/ctxhttp"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJwksRefreshInterval is used to refresh the key set if the JWKS response
+	// doesn't contain the Cache-Control max-age directive.
+	DefaultJwksRefreshInterval = time.Hour
+
+	// DefaultJwksMinRefreshInterval limits how often the key set can be fetched from the
+	// authorization server, including the forced refreshes caused by an unknown key identifier.
+	DefaultJwksMinRefreshInterval = time.Minute
+
+	// jwksFetchTimeout limits the duration of the background key set refresh.
+	jwksFetchTimeout = 30 * time.Second
+)
+
+// tokenHeader contains the JOSE header parameters of the signed token.
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// keySet caches the JSON Web Key Set of the authorization server and refreshes it when the
+// cache lifetime is over or when a token is signed by an unknown key.
+type keySet struct {
+	url                string
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu         sync.RWMutex
+	keys       []jwk.Key
+	etag       string
+	expiry     time.Time
+	fetched    time.Time
+	refreshing bool
+
+	// fetchMu serializes the requests to the JWKS endpoint.
+	fetchMu sync.Mutex
+
+	// do sends the request to the JWKS endpoint, it's set by the verifier to retry the failed requests.
+	do func(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error)
+
+	// logf reports the failures of the background refresh.
+	logf func(format string, v ...interface{})
+
+	// now returns the current time of the clock of the verifier.
+	now func() time.Time
+}
+
+func newKeySet(url string, refreshInterval time.Duration, minRefreshInterval time.Duration) *keySet {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultJwksRefreshInterval
+	}
+	if minRefreshInterval <= 0 {
+		minRefreshInterval = DefaultJwksMinRefreshInterval
+	}
+	return &keySet{
+		url:                url,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		now:                time.Now,
+	}
+}
+
+// lookup returns the keys with the given key identifier, or all keys if the identifier is empty.
+// The key set is fetched on the first use, refreshed in the background when the cache lifetime
+// is over and refreshed once synchronously when the key identifier is unknown.
+func (s *keySet) lookup(ctx context.Context, kid string) ([]jwk.Key, error) {
+	s.mu.RLock()
+	keys, expiry, fetched := s.keys, s.expiry, s.fetched
+	s.mu.RUnlock()
+
+	if keys == nil {
+		if err := s.refresh(ctx, fetched); err != nil {
+			return nil, err
+		}
+	} else if s.now().After(expiry) {
+		s.refreshInBackground(ctx)
+	}
+
+	if found := s.find(kid); len(found) > 0 {
+		return found, nil
+	}
+
+	s.mu.RLock()
+	fetched = s.fetched
+	s.mu.RUnlock()
+	if kid != "" && s.now().Sub(fetched) >= s.minRefreshInterval {
+		if err := s.refresh(ctx, fetched); err != nil {
+			return nil, err
+		}
+		if found := s.find(kid); len(found) > 0 {
+			return found, nil
+		}
+	}
+
+	return nil, validationError("", ErrUnknownSigningKey)
+}
+
+func (s *keySet) find(kid string) []jwk.Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []jwk.Key
+	for _, k := range s.keys {
+		if kid == "" || k.KeyID() == kid {
+			found = append(found, k)
+		}
+	}
+	return found
+}
+
+func (s *keySet) refreshInBackground(ctx context.Context) {
+	s.mu.Lock()
+	if s.refreshing {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing = true
+	fetched := s.fetched
+	s.mu.Unlock()
+
+	// The refresh must outlive the request that triggered it, so only the values are taken from its context.
+	bg := internal.Detach(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(bg, jwksFetchTimeout)
+		defer cancel()
+		if err := s.refresh(ctx, fetched); err != nil && s.logf != nil {
+			s.logf("jwtverifier: cannot refresh key set: %v", err)
+		}
+
+		s.mu.Lock()
+		s.refreshing = false
+		s.mu.Unlock()
+	}()
+}
+
+// refresh fetches the key set unless it has been fetched by another caller after the given time.
+func (s *keySet) refresh(ctx context.Context, after time.Time) error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.RLock()
+	fetched, etag, cached := s.fetched, s.etag, s.keys != nil
+	s.mu.RUnlock()
+	if fetched.After(after) && cached {
+		return nil
+	}
+
+	if err := checkEndpoint("jwks", s.url); err != nil {
+		return err
+	}
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", s.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if etag != "" && cached {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return req, nil
+	}
+
+	var r *http.Response
+	var err error
+	if s.do != nil {
+		r, err = s.do(ctx, newRequest)
+	} else {
+		var req *http.Request
+		if req, err = newRequest(); err != nil {
+			return err
+		}
+		r, err = ctxhttp.Do(ctx, internal.ContextClient(ctx), req)
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("oauth2: cannot fetch key set: %v", err)
+	}
+
+	now := s.now()
+	lifetime := s.lifetime(r.Header.Get("Cache-Control"))
+
+	if r.StatusCode == http.StatusNotModified && cached {
+		s.mu.Lock()
+		s.fetched, s.expiry = now, now.Add(lifetime)
+		s.mu.Unlock()
+		return nil
+	}
+	if code := r.StatusCode; code < 200 || code > 299 {
+		return newRetrieveError(r, body)
+	}
+
+	set, err := jwk.Parse(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = set.Keys
+	s.etag = r.Header.Get("ETag")
+	s.fetched, s.expiry = now, now.Add(lifetime)
+	s.mu.Unlock()
+	return nil
+}
+
+// lifetime returns how long the fetched key set can be used without revalidation.
+// The max-age directive is honoured, but the key set is never refreshed more often than
+// the minimal refresh interval.
+func (s *keySet) lifetime(cacheControl string) time.Duration {
+	lifetime := s.refreshInterval
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			lifetime = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if sec, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				lifetime = time.Duration(sec) * time.Second
+			}
+		}
+	}
+	if lifetime < s.minRefreshInterval {
+		lifetime = s.minRefreshInterval
+	}
+	return lifetime
+}
+
+// verifySignature checks the signing algorithm and the signature of the compact serialized token
+// with the keys of the authorization server and returns the verified payload and the token header.
+func (j *JwtVerifier) verifySignature(ctx context.Context, token string) ([]byte, *tokenHeader, error) {
+	h, err := parseTokenHeader(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !j.isAllowedAlgorithm(h.Alg) {
+		return nil, nil, validationError("", ErrUnsupportedAlgorithm)
+	}
+
+	keys, err := j.keys.lookup(ctx, h.Kid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, key := range keys {
+		if key.Algorithm() != "" && key.Algorithm() != h.Alg {
+			continue
+		}
+		raw, err := key.Materialize()
+		if err != nil {
+			continue
+		}
+		if payload, err := jws.Verify([]byte(token), jwa.SignatureAlgorithm(h.Alg), raw); err == nil {
+			return payload, h, nil
+		}
+	}
+	return nil, nil, validationError("", ErrInvalidSignature)
+}
+
+// isAllowedAlgorithm checks the token signing algorithm against the configured algorithms,
+// the algorithms announced by the provider or the RS256 algorithm defined by OpenID Connect as default.
+func (j *JwtVerifier) isAllowedAlgorithm(alg string) bool {
+	if alg == "" || alg == string(jwa.NoSignature) {
+		return false
+	}
+	allowed := j.config.SigningAlgorithms
+	if len(allowed) == 0 && j.metadata != nil {
+		allowed = j.metadata.IdTokenSigningAlgValuesSupported
+	}
+	if len(allowed) == 0 {
+		allowed = []string{string(jwa.RS256)}
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTokenHeader decodes the JOSE header of the compact serialized token without verification.
+func parseTokenHeader(token string) (*tokenHeader, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwtverifier: token isn't a compact serialized JWS")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("jwtverifier: cannot decode token header: %v", err)
+	}
+	h := &tokenHeader{}
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, fmt.Errorf("jwtverifier: cannot decode token header: %v", err)
+	}
+	return h, nil
+}
@@ -0,0 +1,184 @@
+package jwtverifier
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testSigner struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T, kid string) *testSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate key: %s", err)
+	}
+	return &testSigner{kid: kid, key: key}
+}
+
+func (s *testSigner) sign(t *testing.T, claims interface{}) string {
+	return s.signTyped(t, "", claims)
+}
+
+func (s *testSigner) signTyped(t *testing.T, typ string, claims interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("unable to marshal claims: %s", err)
+	}
+	h := &jws.StandardHeaders{}
+	h.Set(jws.KeyIDKey, s.kid)
+	if typ != "" {
+		h.Set(jws.TypeKey, typ)
+	}
+	token, err := jws.Sign(payload, jwa.RS256, s.key, jws.WithHeaders(h))
+	if err != nil {
+		t.Fatalf("unable to sign token: %s", err)
+	}
+	return string(token)
+}
+
+func jwksBody(t *testing.T, signers ...*testSigner) []byte {
+	set := jwk.Set{}
+	for _, s := range signers {
+		k, err := jwk.New(&s.key.PublicKey)
+		if err != nil {
+			t.Fatalf("unable to create jwk: %s", err)
+		}
+		k.Set(jwk.KeyIDKey, s.kid)
+		set.Keys = append(set.Keys, k)
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("unable to marshal jwks: %s", err)
+	}
+	return b
+}
+
+// jwksServer serves the key set of the current signers and counts the requests.
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	signers  []*testSigner
+	requests int32
+}
+
+func newJwksServer(t *testing.T, signers ...*testSigner) *jwksServer {
+	s := &jwksServer{signers: signers}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwksBody(t, s.signers...))
+	}))
+	return s
+}
+
+func (s *jwksServer) rotate(signers ...*testSigner) {
+	s.mu.Lock()
+	s.signers = signers
+	s.mu.Unlock()
+}
+
+func TestValidateIdToken_CachedKeySet(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	token := signer.sign(t, idTokenClaims(ts.URL))
+	for i := 0; i < 3; i++ {
+		if _, err := jwt.ValidateIdToken(context.Background(), token); err != nil {
+			t.Fatalf("unable to validate id token: %s", err)
+		}
+	}
+	if n := atomic.LoadInt32(&ts.requests); n != 1 {
+		t.Errorf("Key set must be fetched once, but it was fetched %d times", n)
+	}
+}
+
+func TestValidateIdToken_KeyRotation(t *testing.T) {
+	old, next := newTestSigner(t, "key1"), newTestSigner(t, "key2")
+	ts := newJwksServer(t, old)
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, JwksMinRefreshInterval: time.Nanosecond})
+	claims := idTokenClaims(ts.URL)
+	if _, err := jwt.ValidateIdToken(context.Background(), old.sign(t, claims)); err != nil {
+		t.Fatalf("unable to validate id token: %s", err)
+	}
+
+	ts.rotate(next)
+	if _, err := jwt.ValidateIdToken(context.Background(), next.sign(t, claims)); err != nil {
+		t.Fatalf("unable to validate id token signed by rotated key: %s", err)
+	}
+	if n := atomic.LoadInt32(&ts.requests); n != 2 {
+		t.Errorf("Key set must be fetched twice, but it was fetched %d times", n)
+	}
+}
+
+func TestValidateIdToken_UnknownKey(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	claims := idTokenClaims(ts.URL)
+	if _, err := jwt.ValidateIdToken(context.Background(), signer.sign(t, claims)); err != nil {
+		t.Fatalf("unable to validate id token: %s", err)
+	}
+
+	unknown := newTestSigner(t, "key2")
+	if _, err := jwt.ValidateIdToken(context.Background(), unknown.sign(t, claims)); err == nil {
+		t.Error("token signed by unknown key should have caused an error")
+	}
+	if n := atomic.LoadInt32(&ts.requests); n != 1 {
+		t.Errorf("Key set must not be refreshed within the minimal interval, but it was fetched %d times", n)
+	}
+}
+
+func TestKeySet_NotModified(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	var conditional int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "public, max-age=120")
+		w.Write(jwksBody(t, signer))
+	}))
+	defer ts.Close()
+
+	s := newKeySet(ts.URL, 0, time.Second)
+	if _, err := s.lookup(context.Background(), "key1"); err != nil {
+		t.Fatalf("unable to lookup key: %s", err)
+	}
+	if d := time.Until(s.expiry); d < 110*time.Second || d > 120*time.Second {
+		t.Errorf("Key set lifetime must be taken from max-age, got %s", d)
+	}
+	if err := s.refresh(context.Background(), s.fetched); err != nil {
+		t.Fatalf("unable to refresh key set: %s", err)
+	}
+	if atomic.LoadInt32(&conditional) != 1 {
+		t.Error("Key set must be revalidated with the ETag")
+	}
+	if keys := s.find("key1"); len(keys) != 1 {
+		t.Error("Key set must be kept after the not modified response")
+	}
+}
@@ -9,22 +9,38 @@ import (
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/internal"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage/memory"
-	"github.com/lestrrat-go/jwx/jwk"
-	"github.com/lestrrat-go/jwx/jws"
-	"golang.org/x/net/context/ctxhttp"
-	"golang.org/x/oauth2"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // JwtVerifier used to interact with AuthOne authorization server.
 type JwtVerifier struct {
 	config  *Config
-	oauth2  *oauth2.Config
 	storage storage.Adapter
+	keys    *keySet
+
+	clientCredentials clientCredentialsCache
+
+	// lineage keeps the access tokens obtained with each refresh token.
+	lineage *tokenLineage
+
+	// introspections coalesces the concurrent introspections of the same token.
+	introspections internal.FlightGroup
+
+	// breaker protects the authorization server from the requests while it's failing.
+	breaker *circuitBreaker
+
+	httpClient *http.Client
+	clock      Clock
+	logger     Logger
+	hooks      Hooks
+
+	// metadata contains the discovered provider metadata, it's nil if the discovery wasn't used.
+	metadata *providerMetadata
 }
 
 // Config describes a typical 3-legged OpenId Connect flow, with both the
@@ -47,6 +63,79 @@ type Config struct {
 	// Without a slash at the end of the line, this is important.
 	Issuer string
 
+	// JwksRefreshInterval defines how long the key set of the authorization server is used
+	// before it is refreshed, if the JWKS response doesn't define its own lifetime with
+	// the Cache-Control header. The DefaultJwksRefreshInterval is used if it's zero.
+	JwksRefreshInterval time.Duration
+
+	// JwksMinRefreshInterval defines the minimal interval between the requests of the key set,
+	// including the forced refreshes caused by an unknown key identifier.
+	// The DefaultJwksMinRefreshInterval is used if it's zero.
+	JwksMinRefreshInterval time.Duration
+
+	// SigningAlgorithms lists the algorithms the tokens are allowed to be signed with.
+	// If it's empty, the algorithms announced by the discovery document are used, or the RS256 otherwise.
+	SigningAlgorithms []string
+
+	// Leeway is the allowed clock skew between the authorization server and the application
+	// used to check the time based claims of the tokens.
+	Leeway time.Duration
+
+	// Audience lists the values accepted in the aud claim of the access tokens, usually the identifiers
+	// of the API protected by the verifier. If it's empty, the aud claim of the introspected tokens isn't
+	// checked, while the JWT access tokens verified locally must name the ClientID in the aud claim.
+	//
+	// Together with the AcceptedClients it allows the verifier to work as a resource server accepting
+	// the access tokens issued to other clients as long as they're issued for the API.
+	Audience []string
+
+	// AcceptedClients lists the clients, in addition to the ClientID, whose access tokens are accepted.
+	// The AnyClient accepts the access tokens of any client issued for the Audience.
+	AcceptedClients []string
+
+	// TokenPolicy is called for every access token passed the other checks to make a custom decision,
+	// the token is rejected with the returned error if it isn't nil.
+	TokenPolicy func(ctx context.Context, t *IntrospectToken) error
+
+	// NegativeCacheTTL defines how long the inactive tokens and the tokens of another client are cached
+	// by Introspect. The DefaultNegativeCacheTTL is used if it's zero, the negative caching is disabled
+	// if it's negative.
+	NegativeCacheTTL time.Duration
+
+	// CachePolicy defines how the cached introspections of the active tokens are revalidated.
+	CachePolicy CachePolicy
+
+	// Retry defines how the idempotent requests to the authorization server are retried.
+	Retry RetryPolicy
+
+	// Breaker defines when the requests to the authorization server are rejected without sending
+	// to protect the failing server.
+	Breaker BreakerPolicy
+
+	// TokenValidation selects how the access tokens are checked by the Authenticate method.
+	// The access tokens are introspected by default.
+	TokenValidation TokenValidation
+
+	// ClientAuthMethod is the method used to authenticate the client at the token, introspection
+	// and revocation endpoints: ClientSecretBasic, ClientSecretPost, PrivateKeyJwt or ClientAuthNone.
+	// If it's empty, the ClientSecretBasic is used for the clients with a secret and the ClientAuthNone otherwise.
+	ClientAuthMethod string
+
+	// PrivateKey is the *rsa.PrivateKey or *ecdsa.PrivateKey used to sign the client assertion
+	// of the PrivateKeyJwt client authentication method.
+	PrivateKey interface{}
+
+	// PrivateKeyID is the key identifier of the PrivateKey registered at the authorization server.
+	PrivateKeyID string
+
+	// PrivateKeyAlgorithm is the algorithm used to sign the client assertion.
+	// If it's empty, RS256 is used for RSA keys and ES256 for ECDSA keys.
+	PrivateKeyAlgorithm string
+
+	// Endpoints allows to override the URLs of the authorization server endpoints.
+	// Empty values are ignored and the discovered or default URLs are used instead.
+	Endpoints Endpoints
+
 	// endpoint contains the resource server's token endpoint
 	// URLs. These are constants specific to each server and are
 	// often available via tenant-specific setting for each
@@ -99,9 +188,65 @@ type endpoint struct {
 
 	// logoutUrl is the URL to log out user with deletion session and cookie on OAuth authentication server.
 	logoutUrl string
+
+	// deviceAuthURL is the URL to start the device authorization grant for the input-constrained devices.
+	deviceAuthURL string
+}
+
+// override replaces the endpoint URLs with the non-empty values of the given endpoints.
+func (e *endpoint) override(o Endpoints) {
+	for _, v := range []struct {
+		dst *string
+		src string
+	}{
+		{&e.authURL, o.AuthURL},
+		{&e.tokenURL, o.TokenURL},
+		{&e.userInfoURL, o.UserInfoURL},
+		{&e.introspectURL, o.IntrospectURL},
+		{&e.revokeUrl, o.RevokeURL},
+		{&e.logoutUrl, o.LogoutURL},
+		{&e.jwksUrl, o.JwksURL},
+		{&e.deviceAuthURL, o.DeviceAuthURL},
+	} {
+		if v.src != "" {
+			*v.dst = v.src
+		}
+	}
+}
+
+// Endpoints contains the URLs of the authorization server endpoints which should be used
+// instead of the default or discovered ones.
+type Endpoints struct {
+	// AuthURL is the URL of the authorization endpoint.
+	AuthURL string
+
+	// TokenURL is the URL of the token endpoint.
+	TokenURL string
+
+	// UserInfoURL is the URL of the UserInfo endpoint.
+	UserInfoURL string
+
+	// IntrospectURL is the URL of the token introspection endpoint.
+	IntrospectURL string
+
+	// RevokeURL is the URL of the token revocation endpoint.
+	RevokeURL string
+
+	// LogoutURL is the URL of the end session endpoint.
+	LogoutURL string
+
+	// JwksURL is the URL of the JSON Web Key Set document.
+	JwksURL string
+
+	// DeviceAuthURL is the URL of the device authorization endpoint.
+	DeviceAuthURL string
 }
 
 // NewJwtVerifier create new instance of verifier with given configuration.
+// The endpoint URLs are built from the Issuer with the default AuthOne paths.
+//
+// The options are either Option or storage.Adapter, the options of any other type are ignored
+// and reported to the logger. Use New to check the configuration.
 func NewJwtVerifier(config Config, options ...interface{}) *JwtVerifier {
 	config.endpoint = endpoint{
 		authURL:       config.Issuer + "/oauth2/auth",
@@ -111,36 +256,62 @@ func NewJwtVerifier(config Config, options ...interface{}) *JwtVerifier {
 		introspectURL: config.Issuer + "/oauth2/introspect",
 		logoutUrl:     config.Issuer + "/oauth2/logout",
 		jwksUrl:       config.Issuer + "/.well-known/jwks.json",
+		deviceAuthURL: config.Issuer + "/oauth2/device/auth",
 	}
-	conf := &oauth2.Config{
-		ClientID:     config.ClientID,
-		ClientSecret: config.ClientSecret,
-		Scopes:       config.Scopes,
-		RedirectURL:  config.RedirectURL,
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  config.endpoint.authURL,
-			TokenURL: config.endpoint.tokenURL,
-		},
-	}
+	return newJwtVerifier(config, options...)
+}
 
+func newJwtVerifier(config Config, options ...interface{}) *JwtVerifier {
 	j := &JwtVerifier{
-		config: &config,
-		oauth2: conf,
+		config:  &config,
+		lineage: newTokenLineage(),
+		keys:    newKeySet("", config.JwksRefreshInterval, config.JwksMinRefreshInterval),
+		breaker: &circuitBreaker{policy: config.Breaker},
+	}
+	j.keys.do = func(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+		return j.do(ctx, newRequest, true)
 	}
+	j.keys.logf = j.logf
+	j.keys.now = j.now
+	j.breaker.now = j.now
+	j.setEndpoint(config.endpoint)
 
+	var ignored []interface{}
 	for i := range options {
-		if st, ok := options[i].(storage.Adapter); ok {
-			j.storage = st
+		switch o := options[i].(type) {
+		case Option:
+			if o != nil {
+				o(j)
+			}
+		case storage.Adapter:
+			j.storage = o
+		default:
+			ignored = append(ignored, o)
 		}
 	}
+	// The logger may be set by any of the options, so the ignored ones are reported afterwards.
+	for _, o := range ignored {
+		j.logf("jwtverifier: unsupported option type %T is ignored", o)
+	}
 
 	if j.storage == nil {
-		j.storage = memory.NewStorage(memory.MaxSize)
+		if j.clock != nil {
+			j.storage = memory.NewStorage(memory.MaxSize, j.clock)
+		} else {
+			j.storage = memory.NewStorage(memory.MaxSize)
+		}
 	}
 
 	return j
 }
 
+// setEndpoint sets the endpoint URLs overridden by the Config.Endpoints.
+func (j *JwtVerifier) setEndpoint(e endpoint) {
+	e.override(j.config.Endpoints)
+	j.config.endpoint = e
+	j.keys.url = e.jwksUrl
+}
+
 // SetStorage allow to set adapter for the introspection token.
 // See available adapters in the storage folder.
 func (j *JwtVerifier) SetStorage(a storage.Adapter) {
@@ -179,109 +350,148 @@ func (j *JwtVerifier) CreateAuthUrl(state string, options ...AuthUrlOption) stri
 // Exchange converts an authorization code into a token.
 //
 // It is used after a resource provider redirects the user back
-// to the Redirect URI (the URL obtained from AuthCodeURL).
+// to the Redirect URI (the URL obtained from CreateAuthUrl).
 //
-// The provided context optionally controls which HTTP client is used. See the HTTPClient variable.
+// The provided context optionally controls which HTTP client is used. See the oauth2.HTTPClient variable.
 //
 // The code will be in the *http.Request.FormValue("code"). Before
 // calling Exchange, be sure to validate FormValue("state").
 //
-// Opts may include the PKCE verifier code if previously used in AuthCodeURL.
+// Options may include the PKCE verifier code if previously used in CreateAuthUrl, see CodeVerifierOption.
 // See https://www.oauth.com/oauth2-servers/pkce/ for more info.
-func (j *JwtVerifier) Exchange(ctx context.Context, code string) (*Token, error) {
-	t, err := j.oauth2.Exchange(ctx, code)
-	if err != nil {
-		return nil, err
+func (j *JwtVerifier) Exchange(ctx context.Context, code string, options ...AuthUrlOption) (*Token, error) {
+	form := url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
 	}
-	return &Token{t}, nil
+	if j.config.RedirectURL != "" {
+		form.Set("redirect_uri", j.config.RedirectURL)
+	}
+	for _, o := range options {
+		form.Set(o.Key, o.Value)
+	}
+	return j.retrieveToken(ctx, form)
 }
 
 // Introspect check the token refresh or access is active or not. An active token is neither expired nor revoked.
 // Uses token storage for temporary storage of tokens. If the token has expired or it has been revoked,
 // the information will be deleted from the temporary storage.
+//
+// The token issued to another client or for another audience is rejected with ErrInvalidClient or
+// ErrInvalidAudience as defined by the Config.Audience and Config.AcceptedClients, then the Config.TokenPolicy
+// is applied.
+//
+// The inactive and rejected tokens are cached for the Config.NegativeCacheTTL, the same error is returned
+// for them without the request to the authorization server.
+// The cached introspections of the active tokens are revalidated as defined by the Config.CachePolicy,
+// the Stale field of the returned introspection is set if it's served without the revalidation.
 func (j *JwtVerifier) Introspect(ctx context.Context, token string) (*IntrospectToken, error) {
-	introspect := &IntrospectToken{}
-	if i, _ := j.storage.Get(token); i != nil {
-		if err := json.Unmarshal(i, introspect); err != nil {
-			return nil, err
-		} else {
-			return introspect, nil
+	introspect, cached, err := j.introspectCached(ctx, token)
+	if err == nil {
+		if err = j.applyTokenPolicy(ctx, introspect); err != nil {
+			introspect = nil
+		}
+	}
+	if j.hooks.OnIntrospect != nil {
+		j.hooks.OnIntrospect(introspect, err, cached)
+	}
+	return introspect, err
+}
+
+// introspectCached returns the cached introspection of the token, if it's allowed by the cache policy,
+// or requests it from the authorization server.
+func (j *JwtVerifier) introspectCached(ctx context.Context, token string) (*IntrospectToken, bool, error) {
+	e, err := j.loadIntrospection(token)
+	if err != nil {
+		return nil, true, err
+	}
+	policy := j.config.CachePolicy
+	if e != nil {
+		if e.fresh(policy, j.now()) {
+			return e.Token, true, nil
+		}
+		if policy.StaleWhileRevalidate {
+			j.revalidateInBackground(ctx, token)
+			e.Token.Stale = true
+			return e.Token, true, nil
 		}
 	}
 
-	introspect, err := j.getIntrospect(ctx, j.config.endpoint.introspectURL, j.config.ClientID, j.config.ClientSecret, token)
+	introspect, err := j.introspect(ctx, token)
+	if err != nil && e != nil && policy.StaleIfError && isUnavailable(err) {
+		e.Token.Stale = true
+		return e.Token, true, nil
+	}
+	return introspect, false, err
+}
+
+// introspect requests the introspection of the token from the authorization server and caches the result.
+func (j *JwtVerifier) introspect(ctx context.Context, token string) (*IntrospectToken, error) {
+	// The concurrent introspections of the same token share one request to the authorization server.
+	v, err := j.introspections.Do(ctx, token, func(ctx context.Context) (interface{}, error) {
+		return j.getIntrospect(ctx, j.config.endpoint.introspectURL, token)
+	})
 	if err != nil {
 		return nil, err
 	}
+	// Every caller gets its own copy of the shared result.
+	shared := *v.(*IntrospectToken)
+	introspect := &shared
 	if false == introspect.Active {
-		return nil, errors.New("token isn't active")
+		j.storeNegative(token, negativeInactive)
+		return nil, ErrTokenInactive
 	}
-	if j.config.ClientID != introspect.ClientID {
-		return nil, errors.New("token is owned by another client")
+	if err := j.checkAudienceAndClient(introspect.Aud, introspect.ClientID); err != nil {
+		if errors.Is(err, ErrInvalidAudience) {
+			j.storeNegative(token, negativeAnotherAudience)
+		} else {
+			j.storeNegative(token, negativeAnotherClient)
+		}
+		return nil, err
 	}
 
-	if i, err := json.Marshal(introspect); err == nil {
-		if err := j.storage.Set(token, introspect.Exp, i); err != nil {
-			return nil, err
-		}
+	if err := j.storeIntrospection(token, introspect); err != nil {
+		return nil, err
 	}
 
 	return introspect, nil
 }
 
 // GetUserInfo via UserInfo endpoint with uses AccessToken by authenticate header.
+// The request is authorized by the access token itself, so the client authentication isn't used.
 // The claims are packaged in a JSON object where the sub member denotes the subject (end-user) identifier.
 func (j *JwtVerifier) GetUserInfo(ctx context.Context, token string) (*UserInfo, error) {
 	return j.getUserInfo(ctx, token, j.config.endpoint.userInfoURL)
 }
 
-// ValidateIdToken used to check the ID Token and returns its claims (as custom json object) in the event of its validity.
-func (j *JwtVerifier) ValidateIdToken(ctx context.Context, token string) (*IdToken, error) {
-	// UNDONE: We must use application context
-	//token, err := j.
-	set, err := jwk.Fetch(j.config.endpoint.jwksUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	keys := set.Keys[0]
-	verified, err := jws.VerifyWithJWK([]byte(token), keys)
-	if err != nil {
-		return nil, err
-	}
-	t := &IdToken{}
-	err = json.Unmarshal(verified, t)
-	if err != nil {
-		return nil, err
-	}
-	if t.Aud[0] != j.config.ClientID {
-		return nil, errors.New("token is owned by another client")
-	}
-	return t, nil
-}
-
 // Revoke used to invalidate the specified token and, if applicable, other tokens based on the same
-// authorisation grant.
-func (j *JwtVerifier) Revoke(ctx context.Context, token string) error {
-	return j.revokeToken(ctx, token, j.config.endpoint.revokeUrl)
+// authorisation grant, as described in RFC 7009. The optional hint helps the authorization server to
+// find the token faster.
+//
+// The cached introspection of the token is removed from the storage. When a refresh token is revoked,
+// the cached introspections of the access tokens obtained with it by this verifier are removed too.
+func (j *JwtVerifier) Revoke(ctx context.Context, token string, hint ...TokenTypeHint) error {
+	var h TokenTypeHint
+	if len(hint) > 0 {
+		h = hint[0]
+	}
+	return j.revokeToken(ctx, token, h, j.config.endpoint.revokeUrl)
 }
 
 // CreateLogoutUrl create an URL to send the user to the logging out step with return back to the url.
-func (j *JwtVerifier) CreateLogoutUrl(url string) string {
-	return fmt.Sprintf("%s?redirect_uri=%s", j.config.endpoint.logoutUrl, url)
+//
+// Deprecated: use CreateLogoutRequestUrl which supports the parameters of the OpenID Connect RP-Initiated Logout.
+func (j *JwtVerifier) CreateLogoutUrl(redirectURL string) string {
+	return appendQuery(j.config.endpoint.logoutUrl, url.Values{"redirect_uri": {redirectURL}})
 }
 
-func (j *JwtVerifier) getIntrospect(ctx context.Context, introspectURL string, clientId string, clientSecret string, token string) (*IntrospectToken, error) {
-	form := url.Values{"client_id": {clientId}, "secret": {clientSecret}, "token": {token}}
-	req, err := http.NewRequest("POST", introspectURL, strings.NewReader(form.Encode()))
-	if err != nil {
+func (j *JwtVerifier) getIntrospect(ctx context.Context, introspectURL string, token string) (*IntrospectToken, error) {
+	if err := checkEndpoint("introspection", introspectURL); err != nil {
 		return nil, err
 	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
-
-	r, err := ctxhttp.Do(ctx, internal.ContextClient(ctx), req)
+	r, err := j.do(ctx, func() (*http.Request, error) {
+		return j.newFormRequest(introspectURL, url.Values{"token": {token}})
+	}, true)
 	if err != nil {
 		return nil, err
 	}
@@ -292,10 +502,7 @@ func (j *JwtVerifier) getIntrospect(ctx context.Context, introspectURL string, c
 		return nil, fmt.Errorf("oauth2: cannot fetch introspect token: %v", err)
 	}
 	if code := r.StatusCode; code < 200 || code > 299 {
-		return nil, &RetrieveError{
-			Response: r,
-			Body:     body,
-		}
+		return nil, newRetrieveError(r, body)
 	}
 
 	t := &IntrospectToken{}
@@ -304,15 +511,18 @@ func (j *JwtVerifier) getIntrospect(ctx context.Context, introspectURL string, c
 }
 
 func (j *JwtVerifier) getUserInfo(ctx context.Context, t string, userInfoURL string) (*UserInfo, error) {
-	req, err := http.NewRequest("GET", userInfoURL, strings.NewReader(""))
-	if err != nil {
+	if err := checkEndpoint("userinfo", userInfoURL); err != nil {
 		return nil, err
 	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t))
-
-	r, err := ctxhttp.Do(ctx, internal.ContextClient(ctx), req)
+	r, err := j.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", userInfoURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t))
+		return req, nil
+	}, true)
 	if err != nil {
 		return nil, err
 	}
@@ -323,10 +533,7 @@ func (j *JwtVerifier) getUserInfo(ctx context.Context, t string, userInfoURL str
 		return nil, fmt.Errorf("oauth2: cannot fetch user info: %v", err)
 	}
 	if code := r.StatusCode; code < 200 || code > 299 {
-		return nil, &RetrieveError{
-			Response: r,
-			Body:     body,
-		}
+		return nil, newRetrieveError(r, body)
 	}
 
 	i := &UserInfo{}
@@ -334,21 +541,38 @@ func (j *JwtVerifier) getUserInfo(ctx context.Context, t string, userInfoURL str
 	return i, err
 }
 
-func (j *JwtVerifier) revokeToken(ctx context.Context, token string, revokeUrl string) error {
+func (j *JwtVerifier) revokeToken(ctx context.Context, token string, hint TokenTypeHint, revokeUrl string) error {
+	if err := checkEndpoint("revocation", revokeUrl); err != nil {
+		return err
+	}
 	form := url.Values{"token": {token}}
-	req, err := http.NewRequest("POST", revokeUrl, strings.NewReader(form.Encode()))
-	req.Header.Set("Accept", "application/json")
-
-	r, err := ctxhttp.Do(ctx, internal.ContextClient(ctx), req)
+	if hint != "" {
+		form.Set("token_type_hint", string(hint))
+	}
+	r, err := j.do(ctx, func() (*http.Request, error) {
+		return j.newFormRequest(revokeUrl, form)
+	}, true)
 	if err != nil {
 		return err
 	}
 	defer r.Body.Close()
 
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("oauth2: cannot revoke token: %v", err)
+	}
 	if code := r.StatusCode; code < 200 || code > 299 {
-		return &RetrieveError{Response: r}
+		return newRetrieveError(r, body)
 	}
 
-	j.storage.Delete(token)
+	j.purgeRevoked(token, hint)
+	return nil
+}
+
+// checkEndpoint returns an error if the URL of the endpoint is neither discovered nor configured.
+func checkEndpoint(name string, u string) error {
+	if u == "" {
+		return fmt.Errorf("jwtverifier: %s endpoint isn't configured", name)
+	}
 	return nil
 }
@@ -252,7 +252,7 @@ func TestIntrospect_AnotherClient(t *testing.T) {
 func TestCreateLogoutUrl(t *testing.T) {
 	jwt := createJwtVerifier("http://localhost")
 	url := jwt.CreateLogoutUrl("http://mysite.com/")
-	expected := "http://localhost/oauth2/logout?redirect_uri=http://mysite.com/"
+	expected := "http://localhost/oauth2/logout?redirect_uri=http%3A%2F%2Fmysite.com%2F"
 	if expected != url {
 		t.Errorf("Invalid logout URL [%s], expected [%s]", url, expected)
 	}
@@ -0,0 +1,90 @@
+package jwtverifier
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidLogoutState is returned if the state returned to the post logout redirect URI doesn't match
+// the state sent in the logout request.
+var ErrInvalidLogoutState = errors.New("logout state doesn't match")
+
+// LogoutRequest contains the parameters of the RP-Initiated Logout request.
+//
+// See more at:
+// - https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout
+type LogoutRequest struct {
+	// IdTokenHint is the ID Token previously issued to the client, it's passed as a hint about
+	// the end-user's current authenticated session.
+	IdTokenHint string
+
+	// PostLogoutRedirectURI is the URL to which the user is redirected after the logout.
+	// It must be registered for the client at the authorization server.
+	PostLogoutRedirectURI string
+
+	// State is returned back to the post logout redirect URI to maintain the state between
+	// the logout request and the callback.
+	State string
+
+	// UILocales are the preferred languages of the logout user interface, in order of preference.
+	UILocales []string
+}
+
+// CreateLogoutRequestUrl create an URL to send the user to the logging out step as described
+// in the OpenID Connect RP-Initiated Logout. The client identifier is added if the ID Token hint
+// isn't set, so the authorization server can check the post logout redirect URI.
+func (j *JwtVerifier) CreateLogoutRequestUrl(r LogoutRequest) string {
+	v := url.Values{}
+	if r.IdTokenHint != "" {
+		v.Set("id_token_hint", r.IdTokenHint)
+	} else {
+		v.Set("client_id", j.config.ClientID)
+	}
+	if r.PostLogoutRedirectURI != "" {
+		v.Set("post_logout_redirect_uri", r.PostLogoutRedirectURI)
+	}
+	if r.State != "" {
+		v.Set("state", r.State)
+	}
+	if len(r.UILocales) > 0 {
+		v.Set("ui_locales", strings.Join(r.UILocales, " "))
+	}
+	return appendQuery(j.config.endpoint.logoutUrl, v)
+}
+
+// VerifyLogoutState checks that the state returned to the post logout redirect URI matches
+// the expected state sent in the logout request.
+func VerifyLogoutState(r *http.Request, expected string) error {
+	state := r.URL.Query().Get("state")
+	if expected == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expected)) != 1 {
+		return ErrInvalidLogoutState
+	}
+	return nil
+}
+
+// LogoutCallbackHandler returns a handler of the post logout redirect URI which verifies the returned
+// state against the state given by the expected function, for example stored in the user session,
+// and calls the next handler. The requests with an invalid state are rejected with 400 Bad Request.
+func LogoutCallbackHandler(expected func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := VerifyLogoutState(r, expected(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// appendQuery adds the encoded values to the URL, keeping its own query parameters.
+func appendQuery(u string, v url.Values) string {
+	if len(v) == 0 {
+		return u
+	}
+	if strings.Contains(u, "?") {
+		return u + "&" + v.Encode()
+	}
+	return u + "?" + v.Encode()
+}
@@ -0,0 +1,68 @@
+package jwtverifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCreateLogoutRequestUrl(t *testing.T) {
+	jwt := createJwtVerifier("http://localhost")
+	u, err := url.Parse(jwt.CreateLogoutRequestUrl(LogoutRequest{
+		IdTokenHint:           "id.token.hint",
+		PostLogoutRedirectURI: "http://mysite.com/logout?next=/home&x=1",
+		State:                 "state value",
+		UILocales:             []string{"ru", "en"},
+	}))
+	if err != nil {
+		t.Fatalf("unable to parse logout URL: %s", err)
+	}
+	if u.Scheme+"://"+u.Host+u.Path != "http://localhost/oauth2/logout" {
+		t.Errorf("Invalid logout endpoint [%s]", u)
+	}
+	q := u.Query()
+	for k, v := range map[string]string{
+		"id_token_hint":            "id.token.hint",
+		"post_logout_redirect_uri": "http://mysite.com/logout?next=/home&x=1",
+		"state":                    "state value",
+		"ui_locales":               "ru en",
+		"client_id":                "",
+	} {
+		if q.Get(k) != v {
+			t.Errorf("Invalid %s parameter [%s], expected [%s]", k, q.Get(k), v)
+		}
+	}
+}
+
+func TestCreateLogoutRequestUrl_WithoutIdTokenHint(t *testing.T) {
+	jwt := createJwtVerifier("http://localhost")
+	u := jwt.CreateLogoutRequestUrl(LogoutRequest{PostLogoutRedirectURI: "http://mysite.com/"})
+	expected := "http://localhost/oauth2/logout?client_id=CLIENT_ID&post_logout_redirect_uri=http%3A%2F%2Fmysite.com%2F"
+	if u != expected {
+		t.Errorf("Invalid logout URL [%s], expected [%s]", u, expected)
+	}
+}
+
+func TestLogoutCallbackHandler(t *testing.T) {
+	h := LogoutCallbackHandler(func(r *http.Request) string {
+		return "expected-state"
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tc := range []struct {
+		query string
+		code  int
+	}{
+		{"?state=expected-state", http.StatusNoContent},
+		{"?state=another-state", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/logout_result"+tc.query, nil))
+		if w.Code != tc.code {
+			t.Errorf("Invalid status code %d for [%s], expected %d", w.Code, tc.query, tc.code)
+		}
+	}
+}
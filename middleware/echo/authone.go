@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang"
 	"github.com/labstack/echo/v4"
 	"net/http"
@@ -8,11 +9,22 @@ import (
 )
 
 const (
-	ErrorAuthHeaderNotExists = "Authorization header does not exists"
-	ErrorAuthHeaderInvalid   = "Invalid authorization header"
-	ErrorAuthFailed          = "Unable to authenticate user"
+	ErrorAuthHeaderNotExists   = "Authorization header does not exists"
+	ErrorAuthHeaderInvalid     = "Invalid authorization header"
+	ErrorAuthFailed            = "Unable to authenticate user"
+	ErrorTokenInactive         = "Token is expired or revoked"
+	ErrorAuthServerUnavailable = "Authorization server is unavailable"
+	ErrorAuthServerFailed      = "Authorization server failed to check the token"
+	ErrorInsufficientScope     = "Token doesn't have the required scopes"
 )
 
+// TokenContextKey is the key of the checked access token (*jwtverifier.IntrospectToken) in the context,
+// it's used by RequireScopes and RequireAnyScope.
+const TokenContextKey = "token"
+
+// AuthOneJwtWithConfig authenticates the request with the bearer access token checked by cfg.Authenticate
+// and sets the user of the token in the context. The verifier configured as a resource server with
+// the Audience and AcceptedClients accepts the access tokens issued to other clients for the API.
 func AuthOneJwtWithConfig(cfg *jwtverifier.JwtVerifier) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
@@ -41,7 +53,31 @@ func AuthOneJwtCallableWithConfig(cfg *jwtverifier.JwtVerifier, f func(*jwtverif
 	}
 }
 
+// AuthOneJwtWithRegistry authenticates the request like AuthOneJwtWithConfig, but with the verifier of
+// the issuer resolved by the registry from the request or the token.
+func AuthOneJwtWithRegistry(registry *jwtverifier.Registry) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			userInfo, err := authenticateToken(c, func(token string) (*jwtverifier.IntrospectToken, error) {
+				return registry.AuthenticateRequest(c.Request(), token)
+			})
+			if err != nil {
+				return err
+			}
+
+			c.Set("user", userInfo)
+			return next(c)
+		}
+	}
+}
+
 func introspectToken(c echo.Context, cfg *jwtverifier.JwtVerifier) (*jwtverifier.UserInfo, error) {
+	return authenticateToken(c, func(token string) (*jwtverifier.IntrospectToken, error) {
+		return cfg.Authenticate(c.Request().Context(), token)
+	})
+}
+
+func authenticateToken(c echo.Context, authenticate func(token string) (*jwtverifier.IntrospectToken, error)) (*jwtverifier.UserInfo, error) {
 	req := c.Request()
 	auth := req.Header.Get("Authorization")
 	if auth == "" {
@@ -60,13 +96,33 @@ func introspectToken(c echo.Context, cfg *jwtverifier.JwtVerifier) (*jwtverifier
 		}
 	}
 
-	token, err := cfg.Introspect(c.Request().Context(), match[1])
+	token, err := authenticate(match[1])
 	if err != nil {
-		return nil, &echo.HTTPError{
-			Code:    http.StatusUnauthorized,
-			Message: ErrorAuthFailed,
-		}
+		return nil, authError(c, err)
 	}
+	c.Set(TokenContextKey, token)
 
 	return &jwtverifier.UserInfo{UserID: token.Sub}, nil
 }
+
+// authError maps the error of the token check to the HTTP error: the invalid tokens are rejected
+// with 401 Unauthorized, while the failures of the authorization server are reported as 502 or 503.
+func authError(c echo.Context, err error) *echo.HTTPError {
+	var re *jwtverifier.RetrieveError
+	var te *jwtverifier.TransportError
+	switch {
+	case errors.Is(err, jwtverifier.ErrCircuitOpen), errors.As(err, &te):
+		return &echo.HTTPError{Code: http.StatusServiceUnavailable, Message: ErrorAuthServerUnavailable, Internal: err}
+	case errors.As(err, &re):
+		if re.Response != nil && re.Response.StatusCode >= http.StatusInternalServerError {
+			return &echo.HTTPError{Code: http.StatusServiceUnavailable, Message: ErrorAuthServerUnavailable, Internal: err}
+		}
+		return &echo.HTTPError{Code: http.StatusBadGateway, Message: ErrorAuthServerFailed, Internal: err}
+	}
+
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+	if errors.Is(err, jwtverifier.ErrTokenInactive) || errors.Is(err, jwtverifier.ErrTokenExpired) {
+		return &echo.HTTPError{Code: http.StatusUnauthorized, Message: ErrorTokenInactive, Internal: err}
+	}
+	return &echo.HTTPError{Code: http.StatusUnauthorized, Message: ErrorAuthFailed, Internal: err}
+}
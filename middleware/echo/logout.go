@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
+	"github.com/labstack/echo/v4"
+)
+
+// FrontChannelLogout returns the echo handler of the front-channel logout URI which clears the session
+// cookie with the given name, see jwtverifier.JwtVerifier.FrontChannelLogoutHandler.
+func FrontChannelLogout(cfg *jwtverifier.JwtVerifier, cookieName string) echo.HandlerFunc {
+	return echo.WrapHandler(cfg.FrontChannelLogoutHandler(cookieName))
+}
+
+// BackChannelLogout returns the echo handler of the back-channel logout URI,
+// see jwtverifier.JwtVerifier.BackChannelLogoutHandler.
+func BackChannelLogout(cfg *jwtverifier.JwtVerifier) echo.HandlerFunc {
+	return echo.WrapHandler(cfg.BackChannelLogoutHandler())
+}
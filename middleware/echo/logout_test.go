@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFrontChannelLogout(t *testing.T) {
+	e := echo.New()
+	jwtv := jwtverifier.NewJwtVerifier(jwtverifier.Config{ClientID: "CLIENT_ID", Issuer: "http://localhost"})
+
+	req := httptest.NewRequest(http.MethodGet, "/logout?iss=http%3A%2F%2Flocalhost&sid=session_id", nil)
+	req.AddCookie(&http.Cookie{Name: "auth", Value: "token"})
+	res := httptest.NewRecorder()
+	c := e.NewContext(req, res)
+
+	if assert.NoError(t, FrontChannelLogout(jwtv, "auth")(c)) {
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "frame-ancestors http://localhost", res.Header().Get("Content-Security-Policy"))
+		assert.Contains(t, res.Header().Get("Set-Cookie"), "auth=;")
+	}
+}
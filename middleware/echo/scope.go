@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// RequireScopes allows the request only if all of the scopes are granted to the access token checked
+// by the preceding AuthOneJwtWithConfig or AuthOneJwtWithRegistry middleware. Otherwise the request is
+// rejected with 403 Forbidden and the insufficient_scope error as described in RFC 6750 section 3.1.
+func RequireScopes(scopes ...string) echo.MiddlewareFunc {
+	return requireScopes(scopes, func(s jwtverifier.ScopeSet) bool {
+		return s.HasAll(scopes...)
+	})
+}
+
+// RequireAnyScope allows the request only if at least one of the scopes is granted to the access token,
+// see RequireScopes.
+func RequireAnyScope(scopes ...string) echo.MiddlewareFunc {
+	return requireScopes(scopes, func(s jwtverifier.ScopeSet) bool {
+		return s.HasAny(scopes...)
+	})
+}
+
+func requireScopes(scopes []string, granted func(jwtverifier.ScopeSet) bool) echo.MiddlewareFunc {
+	challenge := fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " "))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get(TokenContextKey).(*jwtverifier.IntrospectToken)
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return &echo.HTTPError{Code: http.StatusUnauthorized, Message: ErrorAuthFailed}
+			}
+			if !granted(token.ScopeSet()) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+				return &echo.HTTPError{Code: http.StatusForbidden, Message: ErrorInsufficientScope}
+			}
+			return next(c)
+		}
+	}
+}
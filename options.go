@@ -0,0 +1,165 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/internal"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidConfig is returned by New if the configuration can't be used to interact with the authorization server.
+var ErrInvalidConfig = errors.New("jwtverifier: invalid config")
+
+// Option used to customize the verifier created by New, NewJwtVerifier or NewJwtVerifierWithDiscovery.
+type Option func(*JwtVerifier)
+
+// Clock used to get the current time to check the time based claims and the lifetime of the cached tokens.
+// It's also passed to the default memory storage, see storage.Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// Logger used to report the failures which can't be returned to the caller, for example
+// the failures of the background refresh. The *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Hooks are called on the requests to the authorization server and on the introspections,
+// for example to collect the metrics. Any of them can be nil.
+type Hooks struct {
+	// BeforeRequest is called before every request to the authorization server, including the retries.
+	BeforeRequest func(req *http.Request)
+
+	// AfterResponse is called after every request to the authorization server with its response or error.
+	AfterResponse func(req *http.Request, r *http.Response, err error, duration time.Duration)
+
+	// OnIntrospect is called with the result of every Introspect call, the cached is set if the result
+	// is taken from the storage without the request to the authorization server.
+	OnIntrospect func(introspect *IntrospectToken, err error, cached bool)
+}
+
+// WithStorage sets the adapter used to cache the introspections, see SetStorage.
+func WithStorage(a storage.Adapter) Option {
+	return func(j *JwtVerifier) {
+		j.storage = a
+	}
+}
+
+// WithHTTPClient sets the client used for the requests to the authorization server instead of
+// the http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(j *JwtVerifier) {
+		j.httpClient = c
+	}
+}
+
+// WithClock sets the clock used instead of the system time. The adapter set by WithStorage should be
+// created with the same clock.
+func WithClock(c Clock) Option {
+	return func(j *JwtVerifier) {
+		j.clock = c
+	}
+}
+
+// WithLogger sets the logger of the failures which can't be returned to the caller.
+func WithLogger(l Logger) Option {
+	return func(j *JwtVerifier) {
+		j.logger = l
+	}
+}
+
+// WithLeeway sets the allowed clock skew, see Config.Leeway.
+func WithLeeway(d time.Duration) Option {
+	return func(j *JwtVerifier) {
+		j.config.Leeway = d
+	}
+}
+
+// WithCachePolicy sets the revalidation policy of the cached introspections, see Config.CachePolicy.
+func WithCachePolicy(p CachePolicy) Option {
+	return func(j *JwtVerifier) {
+		j.config.CachePolicy = p
+	}
+}
+
+// WithHooks sets the hooks called on the requests to the authorization server and on the introspections.
+func WithHooks(h Hooks) Option {
+	return func(j *JwtVerifier) {
+		j.hooks = h
+	}
+}
+
+// New create new instance of verifier with given configuration like NewJwtVerifier, but checks the
+// configuration first and returns ErrInvalidConfig if the ClientID is missing or the Issuer isn't
+// an absolute http(s) URL without the trailing slash, query and fragment. The ClientID isn't required
+// for the resource server validating the access tokens locally with the Audience set.
+func New(config Config, options ...Option) (*JwtVerifier, error) {
+	if err := validateConfig(&config); err != nil {
+		return nil, err
+	}
+	opts := make([]interface{}, len(options))
+	for i := range options {
+		opts[i] = options[i]
+	}
+	return NewJwtVerifier(config, opts...), nil
+}
+
+func validateConfig(config *Config) error {
+	// The resource server checking the access tokens locally doesn't interact with the authorization server as a client.
+	resourceServer := config.TokenValidation == LocalValidation && len(config.Audience) > 0
+	if config.ClientID == "" && !resourceServer {
+		return fmt.Errorf("%w: ClientID is required", ErrInvalidConfig)
+	}
+	u, err := url.Parse(config.Issuer)
+	if err != nil {
+		return fmt.Errorf("%w: Issuer is malformed: %v", ErrInvalidConfig, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: Issuer must be an absolute http(s) URL, got %q", ErrInvalidConfig, config.Issuer)
+	}
+	if strings.HasSuffix(config.Issuer, "/") {
+		return fmt.Errorf("%w: Issuer must not have the trailing slash, got %q", ErrInvalidConfig, config.Issuer)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%w: Issuer must not have the query or fragment, got %q", ErrInvalidConfig, config.Issuer)
+	}
+	return nil
+}
+
+// client returns the HTTP client for the requests to the authorization server. The client passed in
+// the context under the oauth2.HTTPClient key takes precedence over the one set by WithHTTPClient.
+func (j *JwtVerifier) client(ctx context.Context) *http.Client {
+	if ctx != nil {
+		if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+			return c
+		}
+		if c, ok := ctx.Value(internal.HTTPClient).(*http.Client); ok {
+			return c
+		}
+	}
+	if j.httpClient != nil {
+		return j.httpClient
+	}
+	return internal.ContextClient(ctx)
+}
+
+// now returns the current time of the configured clock.
+func (j *JwtVerifier) now() time.Time {
+	if j.clock != nil {
+		return j.clock.Now()
+	}
+	return time.Now()
+}
+
+func (j *JwtVerifier) logf(format string, v ...interface{}) {
+	if j.logger != nil {
+		j.logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,287 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage/memory"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type movableClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *movableClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *movableClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+type countingTransport struct {
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for _, tc := range []struct {
+		info   string
+		config Config
+	}{
+		{"missing client id", Config{Issuer: "https://auth1.protocol.one"}},
+		{"missing issuer", Config{ClientID: "CLIENT_ID"}},
+		{"relative issuer", Config{ClientID: "CLIENT_ID", Issuer: "auth1.protocol.one"}},
+		{"malformed issuer", Config{ClientID: "CLIENT_ID", Issuer: "https://auth1 protocol.one:port"}},
+		{"trailing slash", Config{ClientID: "CLIENT_ID", Issuer: "https://auth1.protocol.one/"}},
+		{"issuer with query", Config{ClientID: "CLIENT_ID", Issuer: "https://auth1.protocol.one?tenant=1"}},
+	} {
+		if _, err := New(tc.config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Invalid error for %s [%v], must be [%s]", tc.info, err, ErrInvalidConfig)
+		}
+	}
+
+	if _, err := New(Config{ClientID: "CLIENT_ID", Issuer: "https://auth1.protocol.one/tenant"}); err != nil {
+		t.Errorf("Valid config must be accepted: %s", err)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","exp":4102444800}`))
+	}))
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	st := memory.NewStorage(10)
+	var calls []bool
+	jwt, err := New(
+		Config{ClientID: "CLIENT_ID", Issuer: ts.URL},
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithStorage(st),
+		WithLeeway(time.Minute),
+		WithCachePolicy(CachePolicy{StaleIfError: true}),
+		WithHooks(Hooks{OnIntrospect: func(introspect *IntrospectToken, err error, cached bool) {
+			calls = append(calls, cached)
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create verifier: %s", err)
+	}
+	if jwt.storage != st || jwt.config.Leeway != time.Minute || !jwt.config.CachePolicy.StaleIfError {
+		t.Error("Options must be applied to the verifier")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+			t.Fatalf("unable to introspect token: %s", err)
+		}
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 1 {
+		t.Errorf("Configured HTTP client must be used once, but it was used %d times", n)
+	}
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("Invalid introspection hook calls %v", calls)
+	}
+}
+
+func TestContextHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth2/token":
+			w.Write([]byte(`{"access_token":"access-token","refresh_token":"refresh-token","token_type":"bearer","expires_in":3600}`))
+		default:
+			w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID"}`))
+		}
+	}))
+	defer ts.Close()
+
+	configured := &countingTransport{}
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL}, WithHTTPClient(&http.Client{Transport: configured}))
+
+	transport := &countingTransport{}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	if _, err := jwt.Exchange(ctx, "code"); err != nil {
+		t.Fatalf("unable to exchange code: %s", err)
+	}
+	if _, err := jwt.Refresh(ctx, "refresh-token"); err != nil {
+		t.Fatalf("unable to refresh token: %s", err)
+	}
+	if _, err := jwt.Introspect(ctx, "token1"); err != nil {
+		t.Fatalf("unable to introspect token: %s", err)
+	}
+	if n := atomic.LoadInt32(&transport.requests); n != 3 {
+		t.Errorf("HTTP client of the context must be used for every request, but it was used %d times", n)
+	}
+	if n := atomic.LoadInt32(&configured.requests); n != 0 {
+		t.Errorf("HTTP client of the context must take precedence, but the configured one was used %d times", n)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	claims := idTokenClaims(ts.URL)
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	claims["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+	token := signer.sign(t, claims)
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL}, WithClock(&movableClock{now: time.Now().Add(-90 * time.Minute)}))
+	if _, err := jwt.ValidateIdToken(context.Background(), token); err != nil {
+		t.Errorf("Token must be valid at the time of the clock: %s", err)
+	}
+}
+
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+	l.mu.Unlock()
+}
+
+func TestNewJwtVerifier_UnsupportedOption(t *testing.T) {
+	logger := &testLogger{}
+	var nilOption Option
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: "http://localhost"}, "namespace", nil, nilOption, WithLogger(logger))
+	if jwt.storage == nil {
+		t.Error("Default storage must be set")
+	}
+	if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "string") {
+		t.Errorf("Ignored options must be reported, got %q", logger.lines)
+	}
+}
+
+func TestWithClock_Storage(t *testing.T) {
+	var requests int32
+	clock := &movableClock{now: time.Now()}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","exp":` + strconv.FormatInt(clock.Now().Add(time.Hour).Unix(), 10) + `}`))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL}, WithClock(clock))
+	jwt.Introspect(context.Background(), "token1")
+	jwt.Introspect(context.Background(), "token1")
+	clock.advance(2 * time.Hour)
+	jwt.Introspect(context.Background(), "token1")
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Token must be introspected again after the expiration, requests: %d", n)
+	}
+}
+
+func TestWithClock_KeySet(t *testing.T) {
+	signer1, signer2 := newTestSigner(t, "key1"), newTestSigner(t, "key2")
+	ts := newJwksServer(t, signer1)
+	defer ts.Close()
+
+	clock := &movableClock{now: time.Now()}
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL}, WithClock(clock))
+	if _, err := jwt.ValidateIdToken(context.Background(), signer1.sign(t, idTokenClaims(ts.URL))); err != nil {
+		t.Fatalf("unable to validate id token: %s", err)
+	}
+
+	// The key set can't be refreshed for the unknown key before the minimal refresh interval passes by the clock.
+	ts.rotate(signer2)
+	token := signer2.sign(t, idTokenClaims(ts.URL))
+	if _, err := jwt.ValidateIdToken(context.Background(), token); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("Invalid error [%v], must be [%s]", err, ErrUnknownSigningKey)
+	}
+	clock.advance(2 * DefaultJwksMinRefreshInterval)
+	if _, err := jwt.ValidateIdToken(context.Background(), token); err != nil {
+		t.Errorf("Key set must be refreshed after the minimal refresh interval of the clock: %s", err)
+	}
+}
+
+func TestWithClock_Breaker(t *testing.T) {
+	var failing int32 = 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID"}`))
+	}))
+	defer ts.Close()
+
+	clock := &movableClock{now: time.Now()}
+	jwt := NewJwtVerifier(Config{
+		ClientID: "CLIENT_ID",
+		Issuer:   ts.URL,
+		Breaker:  BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Hour},
+	}, WithClock(clock))
+	jwt.Introspect(context.Background(), "token1")
+	atomic.StoreInt32(&failing, 0)
+	if _, err := jwt.Introspect(context.Background(), "token1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Invalid error of the open circuit [%v], must be [%s]", err, ErrCircuitOpen)
+	}
+
+	clock.advance(2 * time.Hour)
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Errorf("Circuit must let the request through after the open timeout of the clock: %s", err)
+	}
+}
+
+func TestWithClock_TokenSource(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "new-access-token", "refresh_token": "refresh-token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	clock := &movableClock{now: time.Now()}
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL}, WithClock(clock))
+	src := jwt.TokenSource(context.Background(), &Token{Token: &oauth2.Token{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		Expiry:       clock.Now().Add(time.Hour),
+	}})
+
+	for _, tc := range []struct {
+		advance  time.Duration
+		expected string
+		requests int32
+	}{
+		{advance: 0, expected: "access-token", requests: 0},
+		{advance: 2 * time.Hour, expected: "new-access-token", requests: 1},
+		{advance: 30 * time.Minute, expected: "new-access-token", requests: 1},
+		{advance: time.Hour, expected: "new-access-token", requests: 2},
+	} {
+		clock.advance(tc.advance)
+		tok, err := src.Token()
+		if err != nil {
+			t.Fatalf("unable to get token: %s", err)
+		}
+		if tok.AccessToken != tc.expected || atomic.LoadInt32(&requests) != tc.requests {
+			t.Errorf("Unexpected token %q after %d requests, must be %q after %d", tok.AccessToken, atomic.LoadInt32(&requests), tc.expected, tc.requests)
+		}
+	}
+}
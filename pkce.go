@@ -0,0 +1,60 @@
+package jwtverifier
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// CodeChallengeMethodS256 is the only code challenge method supported by the verifier,
+// the plain method isn't used as it gives no protection if the authorization request leaks.
+const CodeChallengeMethodS256 = "S256"
+
+// PKCE contains the Proof Key for Code Exchange parameters of the single authorization request.
+// The code verifier must be kept by the client (e.g. in the user session) until the authorization
+// code is exchanged.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc7636
+type PKCE struct {
+	// CodeVerifier is a high-entropy cryptographic random string sent with the token request.
+	CodeVerifier string
+
+	// CodeChallenge is derived from the CodeVerifier and sent with the authorization request.
+	CodeChallenge string
+
+	// CodeChallengeMethod is the method used to derive the CodeChallenge.
+	CodeChallengeMethod string
+}
+
+// NewPKCE generates a new code verifier and its S256 code challenge.
+func NewPKCE() (*PKCE, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return newPKCE(base64.RawURLEncoding.EncodeToString(b)), nil
+}
+
+func newPKCE(verifier string) *PKCE {
+	sum := sha256.Sum256([]byte(verifier))
+	return &PKCE{
+		CodeVerifier:        verifier,
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: CodeChallengeMethodS256,
+	}
+}
+
+// AuthUrlOptions returns the options to add the code challenge to the authentication form URL.
+// Use it with the CreateAuthUrl method.
+func (p *PKCE) AuthUrlOptions() []AuthUrlOption {
+	return []AuthUrlOption{
+		{Key: "code_challenge", Value: p.CodeChallenge},
+		{Key: "code_challenge_method", Value: p.CodeChallengeMethod},
+	}
+}
+
+// CodeVerifierOption returns the option to send the code verifier with the Exchange method.
+func CodeVerifierOption(verifier string) AuthUrlOption {
+	return AuthUrlOption{Key: "code_verifier", Value: verifier}
+}
@@ -0,0 +1,57 @@
+package jwtverifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewPKCE(t *testing.T) {
+	p, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("unable to generate PKCE: %s", err)
+	}
+	if len(p.CodeVerifier) != 43 {
+		t.Errorf("Invalid code verifier length %d", len(p.CodeVerifier))
+	}
+	if p.CodeChallenge != newPKCE(p.CodeVerifier).CodeChallenge || p.CodeChallengeMethod != "S256" {
+		t.Errorf("Invalid code challenge %#v", p)
+	}
+
+	// Example from RFC 7636 Appendix B.
+	if c := newPKCE("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk").CodeChallenge; c != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("Invalid code challenge [%s]", c)
+	}
+}
+
+func TestCreateAuthUrl_WithPKCE(t *testing.T) {
+	p := newPKCE("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	jwt := createJwtVerifier("http://localhost")
+	url := jwt.CreateAuthUrl("mystate", p.AuthUrlOptions()...)
+	expected := "http://localhost/oauth2/auth?client_id=CLIENT_ID&code_challenge=E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM&code_challenge_method=S256&redirect_uri=REDIRECT_URL&response_type=code&scope=scope&state=mystate"
+	if expected != url {
+		t.Errorf("Invalid auth URL [%s], expected [%s]", url, expected)
+	}
+}
+
+func TestExchangeRequest_WithCodeVerifier(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed reading request body: %s.", err)
+		}
+		if string(body) != "code=exchange-code&code_verifier=verifier&grant_type=authorization_code&redirect_uri=REDIRECT_URL" {
+			t.Errorf("Unexpected exchange payload; got %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "90d64460d14870c08c81352a05dedd3465940a7c", "token_type": "bearer"}`))
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	if _, err := jwt.Exchange(context.Background(), "exchange-code", CodeVerifierOption("verifier")); err != nil {
+		t.Error(err)
+	}
+}
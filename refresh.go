@@ -0,0 +1,116 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/oauth2"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta determines how earlier the token returned by the TokenSource is refreshed
+// before its expiry, it's the same as used by oauth2.ReuseTokenSource.
+const tokenExpiryDelta = 10 * time.Second
+
+// Refresh exchanges the refresh token for a new token. If the authorization server rotates
+// the refresh token, the RefreshTokenRotated of the returned token is set and the old refresh
+// token must not be used anymore. The introspections of the old refresh token and of the access
+// tokens issued with it are removed from the storage.
+func (j *JwtVerifier) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return j.refresh(ctx, &oauth2.Token{RefreshToken: refreshToken})
+}
+
+// TokenSource returns a TokenSource that returns the token until it expires, automatically
+// refreshing it with its refresh token shortly before the expiry. The introspections of the
+// replaced access and refresh tokens are removed from the storage.
+//
+// The returned TokenSource is safe for concurrent use and can be used with oauth2.NewClient.
+func (j *JwtVerifier) TokenSource(ctx context.Context, t *Token) oauth2.TokenSource {
+	var current *oauth2.Token
+	if t != nil {
+		current = t.Token
+	}
+	return &reuseTokenSource{j: j, t: current, refresher: &tokenRefresher{ctx: ctx, j: j, t: current}}
+}
+
+func (j *JwtVerifier) refresh(ctx context.Context, old *oauth2.Token) (*Token, error) {
+	if old.RefreshToken == "" {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {old.RefreshToken},
+	}
+	nt, err := j.retrieveToken(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	t := nt.Token
+	if t.RefreshToken == "" {
+		// The authorization server may keep the refresh token unchanged and omit it in the response.
+		t.RefreshToken = old.RefreshToken
+	}
+
+	// The access tokens issued earlier with the refresh token are replaced by the new one.
+	for _, at := range j.lineage.remove(old.RefreshToken) {
+		if at != t.AccessToken {
+			_ = j.storage.Delete(cacheKey(at))
+		}
+	}
+	if old.AccessToken != "" && old.AccessToken != t.AccessToken {
+		_ = j.storage.Delete(cacheKey(old.AccessToken))
+	}
+	j.lineage.add(t.RefreshToken, t.AccessToken)
+
+	rotated := t.RefreshToken != old.RefreshToken
+	if rotated {
+		_ = j.storage.Delete(cacheKey(old.RefreshToken))
+	}
+	return &Token{Token: t, RefreshTokenRotated: rotated}, nil
+}
+
+// reuseTokenSource returns the token until it expires by the clock of the verifier, like
+// oauth2.ReuseTokenSource does with the wall clock, and synchronizes the refreshes.
+type reuseTokenSource struct {
+	j         *JwtVerifier
+	refresher oauth2.TokenSource
+
+	mu sync.Mutex
+	t  *oauth2.Token
+}
+
+func (s *reuseTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t != nil && s.t.AccessToken != "" && (s.t.Expiry.IsZero() || s.j.now().Add(tokenExpiryDelta).Before(s.t.Expiry)) {
+		return s.t, nil
+	}
+	t, err := s.refresher.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.t = t
+	return t, nil
+}
+
+// tokenRefresher is a TokenSource that refreshes the token with its refresh token.
+// It's used by reuseTokenSource which synchronizes the calls.
+type tokenRefresher struct {
+	ctx context.Context
+	j   *JwtVerifier
+	t   *oauth2.Token
+}
+
+func (r *tokenRefresher) Token() (*oauth2.Token, error) {
+	if r.t == nil {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+	t, err := r.j.refresh(r.ctx, r.t)
+	if err != nil {
+		return nil, err
+	}
+	r.t = t.Token
+	return t.Token, nil
+}
@@ -0,0 +1,95 @@
+package jwtverifier
+
+import (
+	"context"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "grant_type=refresh_token&refresh_token=old-refresh-token" {
+			t.Errorf("Unexpected refresh payload; got %q", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "new-access-token", "refresh_token": "new-refresh-token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	jwt.storage.Set(cacheKey("old-refresh-token"), time.Now().Add(time.Hour).Unix(), []byte(`{}`))
+	jwt.storage.Set(cacheKey("old-access-token"), time.Now().Add(time.Hour).Unix(), []byte(`{}`))
+	jwt.lineage.add("old-refresh-token", "old-access-token")
+
+	tok, err := jwt.Refresh(context.Background(), "old-refresh-token")
+	if err != nil {
+		t.Fatalf("unable to refresh token: %s", err)
+	}
+	if tok.AccessToken != "new-access-token" || tok.RefreshToken != "new-refresh-token" {
+		t.Errorf("Unexpected refreshed token %#v", tok.Token)
+	}
+	if !tok.RefreshTokenRotated {
+		t.Error("Refresh token rotation must be reported")
+	}
+	if i, _ := jwt.storage.Get(cacheKey("old-refresh-token")); i != nil {
+		t.Error("Introspection of the rotated refresh token must be removed from the storage")
+	}
+	if i, _ := jwt.storage.Get(cacheKey("old-access-token")); i != nil {
+		t.Error("Introspection of the replaced access token must be removed from the storage")
+	}
+}
+
+func TestRefresh_NotRotated(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "new-access-token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	tok, err := createJwtVerifier(ts.URL).Refresh(context.Background(), "refresh-token")
+	if err != nil {
+		t.Fatalf("unable to refresh token: %s", err)
+	}
+	if tok.RefreshToken != "refresh-token" || tok.RefreshTokenRotated {
+		t.Errorf("Refresh token must be kept if it isn't rotated, got %#v", tok.Token)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "new-access-token", "refresh_token": "new-refresh-token", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	jwt.storage.Set(cacheKey("old-access-token"), time.Now().Add(time.Hour).Unix(), []byte(`{}`))
+
+	src := jwt.TokenSource(context.Background(), &Token{Token: &oauth2.Token{
+		AccessToken:  "old-access-token",
+		RefreshToken: "old-refresh-token",
+		Expiry:       time.Now().Add(5 * time.Second),
+	}})
+	for i := 0; i < 2; i++ {
+		tok, err := src.Token()
+		if err != nil {
+			t.Fatalf("unable to get token: %s", err)
+		}
+		if tok.AccessToken != "new-access-token" {
+			t.Errorf("Token must be refreshed before its expiry, got %#v", tok)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Token must be refreshed once, but %d requests were made", requests)
+	}
+	if i, _ := jwt.storage.Get(cacheKey("old-access-token")); i != nil {
+		t.Error("Introspection of the replaced access token must be removed from the storage")
+	}
+}
@@ -0,0 +1,192 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage/memory"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IssuerResolver returns the issuer of the request, or the empty string if it can't be resolved.
+type IssuerResolver func(r *http.Request) string
+
+// HostResolver resolves the issuer by the Host header of the request.
+func HostResolver(issuers map[string]string) IssuerResolver {
+	return func(r *http.Request) string {
+		host := r.Host
+		if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+			host = host[:i]
+		}
+		if iss, ok := issuers[r.Host]; ok {
+			return iss
+		}
+		return issuers[host]
+	}
+}
+
+// PathPrefixResolver resolves the issuer by the longest matching prefix of the request path.
+func PathPrefixResolver(issuers map[string]string) IssuerResolver {
+	prefixes := make([]string, 0, len(issuers))
+	for p := range issuers {
+		prefixes = append(prefixes, p)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	return func(r *http.Request) string {
+		for _, p := range prefixes {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return issuers[p]
+			}
+		}
+		return ""
+	}
+}
+
+// HeaderResolver resolves the issuer by the tenant passed in the request header.
+func HeaderResolver(header string, issuers map[string]string) IssuerResolver {
+	return func(r *http.Request) string {
+		return issuers[r.Header.Get(header)]
+	}
+}
+
+// Registry holds one verifier per issuer, so the application can accept the tokens of several
+// authorization servers or tenants. The verifiers share one storage adapter with the keys prefixed
+// by their issuers. The verifiers can be added and removed at any time.
+type Registry struct {
+	storage   storage.Adapter
+	resolvers []IssuerResolver
+
+	mu        sync.RWMutex
+	verifiers map[string]*JwtVerifier
+}
+
+// NewRegistry creates the registry with the storage shared by the verifiers, the memory storage
+// is used if it's nil. The resolvers are tried in order to find the issuer of the request, see ForRequest.
+func NewRegistry(st storage.Adapter, resolvers ...IssuerResolver) *Registry {
+	if st == nil {
+		st = memory.NewStorage(memory.MaxSize)
+	}
+	return &Registry{
+		storage:   st,
+		resolvers: resolvers,
+		verifiers: make(map[string]*JwtVerifier),
+	}
+}
+
+// Add creates the verifier for the issuer of the configuration with New and replaces the previous
+// verifier of the same issuer. The verifier uses the shared storage namespaced by its issuer.
+func (r *Registry) Add(config Config, options ...Option) (*JwtVerifier, error) {
+	options = append([]Option{WithStorage(storage.Namespace(r.storage, config.Issuer))}, options...)
+	j, err := New(config, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.verifiers[config.Issuer] = j
+	r.mu.Unlock()
+	return j, nil
+}
+
+// Remove removes the verifier of the issuer.
+func (r *Registry) Remove(issuer string) {
+	r.mu.Lock()
+	delete(r.verifiers, issuer)
+	r.mu.Unlock()
+}
+
+// Get returns the verifier of the issuer.
+func (r *Registry) Get(issuer string) (*JwtVerifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	j, ok := r.verifiers[issuer]
+	return j, ok
+}
+
+// Issuers returns the sorted issuers of the registered verifiers.
+func (r *Registry) Issuers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	issuers := make([]string, 0, len(r.verifiers))
+	for iss := range r.verifiers {
+		issuers = append(issuers, iss)
+	}
+	sort.Strings(issuers)
+	return issuers
+}
+
+// ForToken returns the verifier of the issuer named in the iss claim of the JWT. The claim is read
+// without the verification, the token must be checked by the returned verifier.
+func (r *Registry) ForToken(token string) (*JwtVerifier, error) {
+	iss, err := tokenIssuer(token)
+	if err != nil {
+		return nil, err
+	}
+	if j, ok := r.Get(iss); ok {
+		return j, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownIssuer, iss)
+}
+
+// ForRequest returns the verifier of the issuer resolved from the request by the resolvers of the registry,
+// or by the iss claim of the token if none of them resolved the issuer. The opaque tokens can be checked
+// only with the issuer resolved from the request.
+func (r *Registry) ForRequest(req *http.Request, token string) (*JwtVerifier, error) {
+	for _, resolve := range r.resolvers {
+		if iss := resolve(req); iss != "" {
+			if j, ok := r.Get(iss); ok {
+				return j, nil
+			}
+			return nil, fmt.Errorf("%w: %q", ErrUnknownIssuer, iss)
+		}
+	}
+	return r.ForToken(token)
+}
+
+// Authenticate checks the JWT access token with the verifier of its issuer, see ForToken.
+func (r *Registry) Authenticate(ctx context.Context, token string) (*IntrospectToken, error) {
+	j, err := r.ForToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return j.Authenticate(ctx, token)
+}
+
+// AuthenticateRequest checks the access token of the request with the verifier of its issuer, see ForRequest.
+func (r *Registry) AuthenticateRequest(req *http.Request, token string) (*IntrospectToken, error) {
+	j, err := r.ForRequest(req, token)
+	if err != nil {
+		return nil, err
+	}
+	return j.Authenticate(req.Context(), token)
+}
+
+// tokenIssuer decodes the iss claim of the compact serialized JWT without verification.
+func tokenIssuer(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("jwtverifier: token isn't a compact serialized JWS")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("jwtverifier: cannot decode token payload: %v", err)
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return "", fmt.Errorf("jwtverifier: cannot decode token payload: %v", err)
+	}
+	if claims.Iss == "" {
+		return "", validationError("iss", ErrMissingClaim)
+	}
+	return claims.Iss, nil
+}
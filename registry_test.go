@@ -0,0 +1,110 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage/memory"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTenantServer(t *testing.T, sub string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","sub":"` + sub + `","exp":4102444800}`))
+	}))
+}
+
+func TestRegistry_ByToken(t *testing.T) {
+	signer1, signer2 := newTestSigner(t, "key1"), newTestSigner(t, "key2")
+	ts1, ts2 := newJwksServer(t, signer1), newJwksServer(t, signer2)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	r := NewRegistry(nil)
+	for _, iss := range []string{ts1.URL, ts2.URL} {
+		if _, err := r.Add(Config{ClientID: "CLIENT_ID", Issuer: iss, Audience: []string{"api"}, TokenValidation: LocalValidation}); err != nil {
+			t.Fatalf("unable to add verifier: %s", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		signer *testSigner
+		issuer string
+	}{
+		{signer: signer1, issuer: ts1.URL},
+		{signer: signer2, issuer: ts2.URL},
+	} {
+		tok, err := r.Authenticate(context.Background(), tc.signer.signTyped(t, "at+jwt", accessTokenClaims(tc.issuer)))
+		if err != nil {
+			t.Errorf("unable to authenticate token of %s: %s", tc.issuer, err)
+		} else if tok.Iss != tc.issuer {
+			t.Errorf("Token is checked by another issuer: %s", tok.Iss)
+		}
+	}
+
+	// The token claims another issuer than the signing key belongs to.
+	forged := signer1.signTyped(t, "at+jwt", accessTokenClaims(ts2.URL))
+	if _, err := r.Authenticate(context.Background(), forged); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("Invalid error for forged token [%v], must be [%s]", err, ErrUnknownSigningKey)
+	}
+
+	r.Remove(ts2.URL)
+	token := signer2.signTyped(t, "at+jwt", accessTokenClaims(ts2.URL))
+	if _, err := r.Authenticate(context.Background(), token); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("Invalid error for removed issuer [%v], must be [%s]", err, ErrUnknownIssuer)
+	}
+	if issuers := r.Issuers(); len(issuers) != 1 || issuers[0] != ts1.URL {
+		t.Errorf("Unexpected issuers: %v", issuers)
+	}
+}
+
+func TestRegistry_ByRequest(t *testing.T) {
+	ts1, ts2 := newTenantServer(t, "user1"), newTenantServer(t, "user2")
+	defer ts1.Close()
+	defer ts2.Close()
+
+	r := NewRegistry(memory.NewStorage(memory.MaxSize),
+		HeaderResolver("X-Tenant", map[string]string{"tenant1": ts1.URL}),
+		PathPrefixResolver(map[string]string{"/tenant2/": ts2.URL, "/tenant": ts1.URL}),
+		HostResolver(map[string]string{"tenant2.example.com": ts2.URL}),
+	)
+	for _, iss := range []string{ts1.URL, ts2.URL} {
+		if _, err := r.Add(Config{ClientID: "CLIENT_ID", Issuer: iss}); err != nil {
+			t.Fatalf("unable to add verifier: %s", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		url    string
+		header string
+		sub    string
+	}{
+		{url: "http://example.com/", header: "tenant1", sub: "user1"},
+		{url: "http://example.com/tenant2/api", sub: "user2"},
+		{url: "http://example.com/tenant1/api", sub: "user1"},
+		{url: "http://tenant2.example.com:8080/api", sub: "user2"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.header != "" {
+			req.Header.Set("X-Tenant", tc.header)
+		}
+		// The same opaque token is cached separately for every issuer.
+		tok, err := r.AuthenticateRequest(req, "opaque_token")
+		if err != nil {
+			t.Errorf("unable to authenticate request to %s: %s", tc.url, err)
+		} else if tok.Sub != tc.sub {
+			t.Errorf("Request to %s is authenticated by another issuer: %s", tc.url, tok.Sub)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if _, err := r.AuthenticateRequest(req, "opaque_token"); err == nil {
+		t.Error("Opaque token without resolved issuer must be rejected")
+	}
+	req.Header.Set("X-Tenant", "tenant1")
+	r.Remove(ts1.URL)
+	if _, err := r.AuthenticateRequest(req, "opaque_token"); !errors.Is(err, ErrUnknownIssuer) {
+		t.Errorf("Invalid error for removed issuer [%v], must be [%s]", err, ErrUnknownIssuer)
+	}
+}
@@ -0,0 +1,262 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/net/context/ctxhttp"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryMinBackoff is the delay before the first retry if the RetryPolicy.MinBackoff is zero.
+	DefaultRetryMinBackoff = 100 * time.Millisecond
+
+	// DefaultRetryMaxBackoff limits the delay between the retries if the RetryPolicy.MaxBackoff is zero.
+	DefaultRetryMaxBackoff = 5 * time.Second
+
+	// DefaultBreakerOpenTimeout is the time the circuit breaker stays open if the BreakerPolicy.OpenTimeout is zero.
+	DefaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without the request to the authorization server while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("jwtverifier: circuit breaker is open")
+
+// RetryPolicy defines how the idempotent requests to the authorization server are retried: the introspection,
+// userinfo, revocation and key set requests. The token requests are never retried, because the authorization
+// codes and the rotated refresh tokens can be used only once.
+//
+// The requests are retried on the network errors, 5xx and 429 response statuses, with the jittered exponential
+// backoff or after the delay from the Retry-After header. The zero value disables the retries.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the failed request.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry, it's doubled for every next retry.
+	// The DefaultRetryMinBackoff is used if it's zero.
+	MinBackoff time.Duration
+
+	// MaxBackoff limits the delay between the retries. The request isn't retried if the Retry-After
+	// header asks to wait longer. The DefaultRetryMaxBackoff is used if it's zero.
+	MaxBackoff time.Duration
+}
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the requests through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen rejects the requests with ErrCircuitOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen lets one trial request through to check whether the authorization server is recovered.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerPolicy defines when the circuit breaker of the requests to the authorization server is opened.
+// The zero value disables the circuit breaker.
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failed requests which opens the circuit breaker.
+	FailureThreshold int
+
+	// OpenTimeout is the time after which the open circuit breaker lets the trial request through.
+	// The DefaultBreakerOpenTimeout is used if it's zero.
+	OpenTimeout time.Duration
+
+	// OnStateChange is called when the state of the circuit breaker is changed.
+	OnStateChange func(from BreakerState, to BreakerState)
+}
+
+// circuitBreaker counts the consecutive failed requests and rejects the requests while it's open.
+type circuitBreaker struct {
+	policy BreakerPolicy
+
+	// now returns the current time of the clock of the verifier.
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow checks whether the request can be sent to the authorization server.
+func (b *circuitBreaker) allow() bool {
+	if b.policy.FailureThreshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	from := b.state
+	allowed := true
+	switch b.state {
+	case BreakerOpen:
+		timeout := b.policy.OpenTimeout
+		if timeout <= 0 {
+			timeout = DefaultBreakerOpenTimeout
+		}
+		if b.now().Sub(b.openedAt) < timeout {
+			allowed = false
+			break
+		}
+		b.state, b.trial = BreakerHalfOpen, true
+	case BreakerHalfOpen:
+		// Only one trial request is sent while the circuit breaker is half-open.
+		allowed = !b.trial
+		b.trial = true
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return allowed
+}
+
+// done records the result of the request allowed by the circuit breaker.
+func (b *circuitBreaker) done(failed bool) {
+	if b.policy.FailureThreshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	from := b.state
+	b.trial = false
+	if failed {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.policy.FailureThreshold {
+			b.state, b.openedAt = BreakerOpen, b.now()
+		}
+	} else {
+		b.failures = 0
+		b.state = BreakerClosed
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release lets the next trial request through without recording the result of the request.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+func (b *circuitBreaker) notify(from BreakerState, to BreakerState) {
+	if from != to && b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(from, to)
+	}
+}
+
+// do sends the request to the authorization server through the circuit breaker and retries
+// the idempotent request as defined by the Config.Retry. The request is created by newRequest
+// for every attempt, so the retry carries, for example, a freshly signed client assertion.
+func (j *JwtVerifier) do(ctx context.Context, newRequest func() (*http.Request, error), idempotent bool) (*http.Response, error) {
+	policy := j.config.Retry
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if !j.breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+		if j.hooks.BeforeRequest != nil {
+			j.hooks.BeforeRequest(req)
+		}
+		start := time.Now()
+		r, err := ctxhttp.Do(ctx, j.client(ctx), req)
+		if j.hooks.AfterResponse != nil {
+			j.hooks.AfterResponse(req, r, err, time.Since(start))
+		}
+		if err != nil {
+			err = &TransportError{Endpoint: req.URL.String(), Err: err}
+		}
+		if ctx.Err() != nil {
+			// The canceled request says nothing about the authorization server.
+			j.breaker.release()
+			return r, err
+		}
+		failed := err != nil || r.StatusCode >= http.StatusInternalServerError || r.StatusCode == http.StatusTooManyRequests
+		j.breaker.done(failed)
+		if !failed || !idempotent || attempt >= policy.MaxRetries {
+			return r, err
+		}
+
+		delay, ok := policy.delay(attempt, r)
+		if !ok {
+			return r, err
+		}
+		if r != nil {
+			r.Body.Close()
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// delay returns the delay before the retry after the attempt. The request isn't retried if
+// the Retry-After header of the response asks to wait longer than the maximum backoff.
+func (p RetryPolicy) delay(attempt int, r *http.Response) (time.Duration, bool) {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = DefaultRetryMinBackoff
+	}
+	if max <= 0 {
+		max = DefaultRetryMaxBackoff
+	}
+
+	if r != nil {
+		if d, ok := retryAfter(r.Header.Get("Retry-After")); ok {
+			return d, d <= max
+		}
+	}
+
+	d := min << uint(attempt)
+	if d > max || d <= 0 {
+		d = max
+	}
+	// The equal jitter keeps the delay between the half and the full backoff.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)), true
+}
+
+// retryAfter parses the Retry-After header with either the delay in seconds or the HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,178 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry_Introspect(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.FormValue("token") != "token1" {
+			t.Errorf("Retried request must keep the body, token [%s]", r.FormValue("token"))
+		}
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID"}`))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Retry: RetryPolicy{MaxRetries: 2}})
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Fatalf("unable to introspect token: %s", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("Introspection must be sent 3 times, but it was sent %d times", n)
+	}
+}
+
+func TestRetry_FreshClientAssertion(t *testing.T) {
+	signer := newTestSigner(t, "client-key")
+	var mu sync.Mutex
+	var assertions []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		assertions = append(assertions, r.FormValue("client_assertion"))
+		attempt := len(assertions)
+		mu.Unlock()
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID"}`))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:         "CLIENT_ID",
+		ClientAuthMethod: PrivateKeyJwt,
+		PrivateKey:       signer.key,
+		PrivateKeyID:     "client-key",
+		Issuer:           ts.URL,
+		Retry:            RetryPolicy{MaxRetries: 1},
+	})
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Fatalf("unable to introspect token: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(assertions) != 2 || assertions[0] == "" || assertions[0] == assertions[1] {
+		t.Errorf("Every attempt must carry a fresh client assertion, got %q", assertions)
+	}
+}
+
+func TestRetry_TokenNotRetried(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Retry: RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond}})
+	if _, err := jwt.Exchange(context.Background(), "exchange-code"); err == nil {
+		t.Fatal("exchange must fail")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Token request must be sent once, but it was sent %d times", n)
+	}
+}
+
+func TestRetry_RetryAfterTooLong(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "CLIENT_ID", Issuer: ts.URL, Retry: RetryPolicy{MaxRetries: 2}})
+	if _, err := jwt.Introspect(context.Background(), "token1"); err == nil {
+		t.Fatal("introspection must fail")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Request must not be retried after the long Retry-After, but it was sent %d times", n)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing int32 = 1
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID"}`))
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	jwt := NewJwtVerifier(Config{
+		ClientID: "CLIENT_ID",
+		Issuer:   ts.URL,
+		Breaker: BreakerPolicy{
+			FailureThreshold: 2,
+			OpenTimeout:      50 * time.Millisecond,
+			OnStateChange: func(from BreakerState, to BreakerState) {
+				mu.Lock()
+				changes = append(changes, from.String()+"->"+to.String())
+				mu.Unlock()
+			},
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := jwt.Introspect(context.Background(), "token1"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Invalid error of the failed request [%v]", err)
+		}
+	}
+	if _, err := jwt.Introspect(context.Background(), "token1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Invalid error of the open circuit [%v], must be [%s]", err, ErrCircuitOpen)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Open circuit must not send the requests, but %d requests were sent", n)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Fatalf("unable to introspect token after recovery: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(expected) {
+		t.Fatalf("Invalid state changes %v, expected %v", changes, expected)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Invalid state changes %v, expected %v", changes, expected)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		d, ok := p.delay(attempt, nil)
+		if !ok || d < max/2 || d > max {
+			t.Errorf("Invalid delay %s of the attempt %d, must be between %s and %s", d, attempt, max/2, max)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIntrospect_ResourceServer(t *testing.T) {
+	var requests int32
+	responses := map[string]string{
+		"first_party":    `{"active":true,"client_id":"WEB_CLIENT","aud":["api"],"exp":4102444800}`,
+		"another_client": `{"active":true,"client_id":"THIRD_PARTY","aud":["api"],"exp":4102444800}`,
+		"another_api":    `{"active":true,"client_id":"WEB_CLIENT","aud":["billing"],"exp":4102444800}`,
+		"no_client":      `{"active":true,"aud":["api"],"exp":4102444800}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(responses[r.FormValue("token")]))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{
+		ClientID:        "API_CLIENT",
+		ClientSecret:    "API_SECRET",
+		Issuer:          ts.URL,
+		Audience:        []string{"api"},
+		AcceptedClients: []string{"WEB_CLIENT", "MOBILE_CLIENT"},
+	})
+	for token, expected := range map[string]error{
+		"first_party":    nil,
+		"another_client": ErrInvalidClient,
+		"another_api":    ErrInvalidAudience,
+		"no_client":      ErrInvalidClient,
+	} {
+		for i := 0; i < 2; i++ {
+			if _, err := jwt.Introspect(context.Background(), token); !errors.Is(err, expected) {
+				t.Errorf("Invalid error for %s [%v], must be [%v]", token, err, expected)
+			}
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("Every token must be introspected once, but %d requests were sent", n)
+	}
+}
+
+func TestIntrospect_AnyClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"active":true,"client_id":"THIRD_PARTY","aud":["api"],"exp":4102444800}`))
+	}))
+	defer ts.Close()
+
+	jwt := NewJwtVerifier(Config{ClientID: "API_CLIENT", Issuer: ts.URL, Audience: []string{"api"}, AcceptedClients: []string{AnyClient}})
+	if _, err := jwt.Introspect(context.Background(), "token1"); err != nil {
+		t.Errorf("Token of any client must be accepted: %s", err)
+	}
+}
+
+func TestVerifyAccessToken_ResourceServerWithoutClient(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	jwt, err := New(Config{
+		Issuer:          ts.URL,
+		Audience:        []string{"api"},
+		AcceptedClients: []string{"web"},
+		TokenValidation: LocalValidation,
+	})
+	if err != nil {
+		t.Fatalf("unable to create resource server: %s", err)
+	}
+
+	for _, tc := range []struct {
+		clientID interface{}
+		err      error
+	}{
+		{clientID: "web", err: nil},
+		{clientID: "mobile", err: ErrInvalidClient},
+		{clientID: "", err: ErrMissingClaim},
+		{clientID: nil, err: ErrMissingClaim},
+	} {
+		claims := accessTokenClaims(ts.URL)
+		if tc.clientID == nil {
+			delete(claims, "client_id")
+		} else {
+			claims["client_id"] = tc.clientID
+		}
+		_, err := jwt.VerifyAccessToken(context.Background(), signer.signTyped(t, "at+jwt", claims))
+		var ve *ValidationError
+		if !errors.Is(err, tc.err) || (tc.err != nil && (!errors.As(err, &ve) || ve.Claim != "client_id")) {
+			t.Errorf("Invalid error for client %v [%v], must be [%v]", tc.clientID, err, tc.err)
+		}
+	}
+}
+
+func TestTokenPolicy(t *testing.T) {
+	signer := newTestSigner(t, "key1")
+	ts := newJwksServer(t, signer)
+	defer ts.Close()
+
+	errForbidden := errors.New("user is blocked")
+	jwt, err := New(Config{
+		Issuer:          ts.URL,
+		Audience:        []string{"api"},
+		AcceptedClients: []string{AnyClient},
+		TokenValidation: LocalValidation,
+		TokenPolicy: func(ctx context.Context, t *IntrospectToken) error {
+			if t.Sub == "blocked" {
+				return errForbidden
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create resource server: %s", err)
+	}
+
+	claims := accessTokenClaims(ts.URL)
+	if _, err := jwt.Authenticate(context.Background(), signer.signTyped(t, "at+jwt", claims)); err != nil {
+		t.Errorf("unable to authenticate token: %s", err)
+	}
+	claims["sub"] = "blocked"
+	if _, err := jwt.Authenticate(context.Background(), signer.signTyped(t, "at+jwt", claims)); !errors.Is(err, errForbidden) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, errForbidden)
+	}
+}
@@ -0,0 +1,110 @@
+package jwtverifier
+
+import (
+	"context"
+	lru "github.com/hashicorp/golang-lru"
+	"sync"
+)
+
+// TokenTypeHint is a hint about the type of the token submitted for revocation.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc7009#section-2.1
+type TokenTypeHint string
+
+const (
+	// AccessTokenHint is used to revoke the access token.
+	AccessTokenHint TokenTypeHint = "access_token"
+
+	// RefreshTokenHint is used to revoke the refresh token and the access tokens issued with it.
+	RefreshTokenHint TokenTypeHint = "refresh_token"
+)
+
+const (
+	// lineageMaxSize limits the number of refresh tokens tracked by the token lineage.
+	lineageMaxSize = 5000
+
+	// lineageMaxAccessTokens limits the number of access tokens tracked per refresh token.
+	lineageMaxAccessTokens = 16
+)
+
+// tokenLineage keeps the access tokens issued together with the refresh token, so their cached
+// introspections can be removed when the refresh token is revoked.
+type tokenLineage struct {
+	mu    sync.Mutex
+	cache *lru.Cache
+}
+
+func newTokenLineage() *tokenLineage {
+	l, _ := lru.New(lineageMaxSize)
+	return &tokenLineage{cache: l}
+}
+
+// add records the access token as derived from the refresh token.
+func (l *tokenLineage) add(refreshToken string, accessToken string) {
+	if refreshToken == "" || accessToken == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var tokens []string
+	if v, ok := l.cache.Get(refreshToken); ok {
+		tokens = v.([]string)
+	}
+	for _, t := range tokens {
+		if t == accessToken {
+			return
+		}
+	}
+	tokens = append(tokens, accessToken)
+	if len(tokens) > lineageMaxAccessTokens {
+		tokens = tokens[len(tokens)-lineageMaxAccessTokens:]
+	}
+	l.cache.Add(refreshToken, tokens)
+}
+
+// remove forgets the refresh token and returns the access tokens derived from it.
+func (l *tokenLineage) remove(refreshToken string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, ok := l.cache.Get(refreshToken)
+	if !ok {
+		return nil
+	}
+	l.cache.Remove(refreshToken)
+	return v.([]string)
+}
+
+// RevokeAll used to invalidate both the refresh and the access token of the token. The refresh token
+// is revoked first, so it can't be used to obtain a new access token while the access token is revoked.
+// Both tokens are revoked even if the revocation of one of them is failed, the first error is returned.
+func (j *JwtVerifier) RevokeAll(ctx context.Context, t *Token) error {
+	if t == nil || t.Token == nil {
+		return nil
+	}
+
+	var err error
+	if t.RefreshToken != "" {
+		err = j.Revoke(ctx, t.RefreshToken, RefreshTokenHint)
+	}
+	if t.AccessToken != "" {
+		if e := j.Revoke(ctx, t.AccessToken, AccessTokenHint); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// purgeRevoked removes the cached introspection of the revoked token. If the token may be a refresh
+// token, the introspections of the access tokens derived from it are removed too.
+func (j *JwtVerifier) purgeRevoked(token string, hint TokenTypeHint) {
+	_ = j.storage.Delete(cacheKey(token))
+	if hint == AccessTokenHint {
+		return
+	}
+	for _, t := range j.lineage.remove(token) {
+		_ = j.storage.Delete(cacheKey(t))
+	}
+}
@@ -0,0 +1,90 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRevoke_Request(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			t.Errorf("Invalid content type [%s]", r.Header.Get("Content-Type"))
+		}
+		if _, _, ok := r.BasicAuth(); !ok {
+			t.Error("Revocation request isn't authenticated")
+		}
+		if r.FormValue("token") != "token1" || r.FormValue("token_type_hint") != "refresh_token" {
+			t.Errorf("Invalid revocation form: %v", r.PostForm)
+		}
+	}))
+	defer ts.Close()
+
+	if err := createJwtVerifier(ts.URL).Revoke(context.Background(), "token1", RefreshTokenHint); err != nil {
+		t.Errorf("unable to revoke token: %s", err)
+	}
+}
+
+func TestRevoke_OAuthError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "unsupported_token_type", "error_description": "Revocation isn't supported"}`))
+	}))
+	defer ts.Close()
+
+	err := createJwtVerifier(ts.URL).Revoke(context.Background(), "token1", AccessTokenHint)
+	var re *RetrieveError
+	if !errors.As(err, &re) || re.ErrorCode != "unsupported_token_type" {
+		t.Errorf("Invalid revocation error [%v]", err)
+	}
+}
+
+func TestRevoke_PurgesDerivedTokens(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token": "access1", "refresh_token": "refresh1", "token_type": "bearer", "expires_in": 3600}`))
+		}
+	}))
+	defer ts.Close()
+
+	jwt := createJwtVerifier(ts.URL)
+	if _, err := jwt.Exchange(context.Background(), "exchange-code"); err != nil {
+		t.Fatalf("unable to exchange code: %s", err)
+	}
+	jwt.storage.Set(cacheKey("access1"), 4102444800, []byte(`{"active": true}`))
+	jwt.storage.Set(cacheKey("refresh1"), 4102444800, []byte(`{"active": true}`))
+
+	if err := jwt.Revoke(context.Background(), "refresh1"); err != nil {
+		t.Fatalf("unable to revoke token: %s", err)
+	}
+	for _, token := range []string{"access1", "refresh1"} {
+		if i, _ := jwt.storage.Get(cacheKey(token)); i != nil {
+			t.Errorf("Introspection of %s must be removed from the storage", token)
+		}
+	}
+}
+
+func TestRevokeAll(t *testing.T) {
+	var mu sync.Mutex
+	var revoked []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		revoked = append(revoked, r.FormValue("token_type_hint")+":"+r.FormValue("token"))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	tok := &Token{Token: &oauth2.Token{AccessToken: "access1", RefreshToken: "refresh1"}}
+	if err := createJwtVerifier(ts.URL).RevokeAll(context.Background(), tok); err != nil {
+		t.Fatalf("unable to revoke tokens: %s", err)
+	}
+	if len(revoked) != 2 || revoked[0] != "refresh_token:refresh1" || revoked[1] != "access_token:access1" {
+		t.Errorf("Invalid revocation requests: %v", revoked)
+	}
+}
@@ -0,0 +1,79 @@
+package jwtverifier
+
+import (
+	"sort"
+	"strings"
+)
+
+// ScopeSet is the set of the scopes granted to the access token.
+type ScopeSet map[string]struct{}
+
+// ParseScope parses the space-separated list of scopes as described in RFC 6749 section 3.3.
+func ParseScope(scope string) ScopeSet {
+	s := ScopeSet{}
+	for _, v := range strings.Fields(scope) {
+		s[v] = struct{}{}
+	}
+	return s
+}
+
+// HasScope checks that the scope is granted.
+func (s ScopeSet) HasScope(scope string) bool {
+	_, ok := s[scope]
+	return ok
+}
+
+// HasAll checks that all of the scopes are granted.
+func (s ScopeSet) HasAll(scopes ...string) bool {
+	for _, v := range scopes {
+		if !s.HasScope(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAny checks that at least one of the scopes is granted, it's false for the empty list.
+func (s ScopeSet) HasAny(scopes ...string) bool {
+	for _, v := range scopes {
+		if s.HasScope(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns the sorted list of the scopes.
+func (s ScopeSet) Strings() []string {
+	scopes := make([]string, 0, len(s))
+	for v := range s {
+		scopes = append(scopes, v)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// String returns the space-separated list of the scopes.
+func (s ScopeSet) String() string {
+	return strings.Join(s.Strings(), " ")
+}
+
+// ScopeSet returns the parsed scopes of the token.
+func (t *IntrospectToken) ScopeSet() ScopeSet {
+	return ParseScope(t.Scope)
+}
+
+// HasScope checks that the scope is granted to the token.
+func (t *IntrospectToken) HasScope(scope string) bool {
+	return t.ScopeSet().HasScope(scope)
+}
+
+// HasAll checks that all of the scopes are granted to the token.
+func (t *IntrospectToken) HasAll(scopes ...string) bool {
+	return t.ScopeSet().HasAll(scopes...)
+}
+
+// HasAny checks that at least one of the scopes is granted to the token.
+func (t *IntrospectToken) HasAny(scopes ...string) bool {
+	return t.ScopeSet().HasAny(scopes...)
+}
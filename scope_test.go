@@ -0,0 +1,32 @@
+package jwtverifier
+
+import (
+	"testing"
+)
+
+func TestScopeSet(t *testing.T) {
+	tok := &IntrospectToken{Scope: " openid  offline orders:read "}
+	s := tok.ScopeSet()
+	if s.String() != "offline openid orders:read" {
+		t.Errorf("Unexpected scopes: %q", s.String())
+	}
+
+	for _, tc := range []struct {
+		name     string
+		result   bool
+		expected bool
+	}{
+		{name: "HasScope", result: tok.HasScope("orders:read"), expected: true},
+		{name: "HasScope of absent scope", result: tok.HasScope("orders"), expected: false},
+		{name: "HasAll", result: tok.HasAll("openid", "orders:read"), expected: true},
+		{name: "HasAll with absent scope", result: tok.HasAll("openid", "orders:write"), expected: false},
+		{name: "HasAll of empty list", result: tok.HasAll(), expected: true},
+		{name: "HasAny", result: tok.HasAny("orders:write", "orders:read"), expected: true},
+		{name: "HasAny with absent scopes", result: tok.HasAny("orders:write", "admin"), expected: false},
+		{name: "HasAny of empty list", result: tok.HasAny(), expected: false},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("%s must be %v", tc.name, tc.expected)
+		}
+	}
+}
@@ -1,8 +1,38 @@
 package storage
 
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTokenNotExists is returned by the adapters if the token isn't stored.
+	ErrTokenNotExists = errors.New("token not exists")
+
+	// ErrTokenIsExpired is returned by the adapters if the stored token has expired.
+	ErrTokenIsExpired = errors.New("token is expired")
+)
+
 // Adapter used for store and retrieve encrypted tokens from oauth introspection endpoint.
 type Adapter interface {
 	Set(token string, expire int64, introspect []byte) error
 	Get(token string) ([]byte, error)
 	Delete(token string) error
 }
+
+// Clock used by the adapters to get the current time to check the expiration of the stored tokens.
+// It can be passed to the adapter constructors to use the same time as the verifier.
+type Clock interface {
+	Now() time.Time
+}
+
+// Indexer is implemented by the adapters able to find the stored tokens by a secondary key,
+// such as the subject or the session identifier. It's used to remove all tokens of the user
+// or the session at once, for example on the back-channel logout.
+type Indexer interface {
+	// Index adds the token to the index with the key. The token is kept in the index until it expires.
+	Index(key string, token string, expire int64) error
+
+	// DeleteIndex removes all tokens added to the index with the key and the index itself.
+	DeleteIndex(key string) error
+}
@@ -1,14 +1,16 @@
 package memory
 
 import (
-	"errors"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
 	lru "github.com/hashicorp/golang-lru"
+	"sync"
 	"time"
 )
 
 const (
+	// ErrorTokenNotExists is the message of the storage.ErrTokenNotExists.
 	ErrorTokenNotExists = "token not exists"
+	// ErrorTokenIsExpired is the message of the storage.ErrTokenIsExpired.
 	ErrorTokenIsExpired = "token is expired"
 	MaxSize             = 5000
 )
@@ -20,13 +22,39 @@ type entry struct {
 
 type tokenStorageMemory struct {
 	cache *lru.Cache
+	clock storage.Clock
+
+	// index keeps the tokens and their expiration time by the secondary key.
+	index   *lru.Cache
+	indexMu *sync.Mutex
 }
 
-func NewStorage(maxSize int) storage.Adapter {
+// NewStorage creates the in-memory storage keeping up to maxSize tokens. The storage.Clock can be passed
+// in the options to check the expiration of the tokens, the system time is used by default.
+func NewStorage(maxSize int, options ...interface{}) storage.Adapter {
 	l, _ := lru.New(maxSize)
-	return tokenStorageMemory{
-		cache: l,
+	i, _ := lru.New(maxSize)
+	tsm := tokenStorageMemory{
+		cache:   l,
+		clock:   systemClock{},
+		index:   i,
+		indexMu: &sync.Mutex{},
+	}
+	for _, o := range options {
+		switch o := o.(type) {
+		case storage.Clock:
+			tsm.clock = o
+		default:
+			panic("Invalid options type for memory storage")
+		}
 	}
+	return tsm
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
 }
 
 func (tsm tokenStorageMemory) Set(token string, expire int64, introspect []byte) error {
@@ -41,16 +69,49 @@ func (tsm tokenStorageMemory) Set(token string, expire int64, introspect []byte)
 func (tsm tokenStorageMemory) Get(token string) ([]byte, error) {
 	if e, ok := tsm.cache.Get(token); ok {
 		entry := e.(*entry)
-		if entry.duration.Before(time.Now()) {
+		if entry.duration.Before(tsm.clock.Now()) {
 			_ = tsm.Delete(token)
-			return nil, errors.New(ErrorTokenIsExpired)
+			return nil, storage.ErrTokenIsExpired
 		}
 		return entry.value, nil
 	}
-	return nil, errors.New(ErrorTokenNotExists)
+	return nil, storage.ErrTokenNotExists
 }
 
 func (tsm tokenStorageMemory) Delete(token string) error {
 	tsm.cache.Remove(token)
 	return nil
 }
+
+func (tsm tokenStorageMemory) Index(key string, token string, expire int64) error {
+	tsm.indexMu.Lock()
+	defer tsm.indexMu.Unlock()
+
+	tokens := map[string]int64{}
+	if v, ok := tsm.index.Get(key); ok {
+		tokens = v.(map[string]int64)
+	}
+	now := tsm.clock.Now().Unix()
+	for t, exp := range tokens {
+		if exp < now {
+			delete(tokens, t)
+		}
+	}
+	tokens[token] = expire
+	tsm.index.Add(key, tokens)
+	return nil
+}
+
+func (tsm tokenStorageMemory) DeleteIndex(key string) error {
+	tsm.indexMu.Lock()
+	v, ok := tsm.index.Get(key)
+	tsm.index.Remove(key)
+	tsm.indexMu.Unlock()
+
+	if ok {
+		for t := range v.(map[string]int64) {
+			_ = tsm.Delete(t)
+		}
+	}
+	return nil
+}
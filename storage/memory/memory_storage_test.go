@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"errors"
 	"fmt"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
 	"testing"
@@ -78,6 +79,62 @@ func TestDeleteToken(t *testing.T) {
 	}
 }
 
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestExpireToken_Clock(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	st := NewStorage(1, clock)
+	if err := st.Set("token", clock.now.Add(time.Hour).Unix(), []byte("token")); err != nil {
+		t.Fatalf("Unable to add token to the memory: %s", err)
+	}
+	if _, err := st.Get("token"); err != nil {
+		t.Errorf("Token must not be expired yet: %s", err)
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	if _, err := st.Get("token"); !errors.Is(err, storage.ErrTokenIsExpired) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, storage.ErrTokenIsExpired)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	st := createStorage(1)
+	if _, err := st.Get("unexiststoken"); !errors.Is(err, storage.ErrTokenNotExists) {
+		t.Errorf("Invalid error [%v], must be [%s]", err, storage.ErrTokenNotExists)
+	}
+}
+
+func TestDeleteIndex(t *testing.T) {
+	st := createStorage(10)
+	exp := time.Now().Add(5 * time.Second).Unix()
+	for _, token := range []string{"token1", "token2", "token3"} {
+		if err := st.Set(token, exp, []byte(token)); err != nil {
+			t.Fatalf("Unable to add token to the memory: %s", err)
+		}
+	}
+	indexer := st.(storage.Indexer)
+	indexer.Index("sub:user", "token1", exp)
+	indexer.Index("sub:user", "token2", exp)
+
+	if err := indexer.DeleteIndex("sub:user"); err != nil {
+		t.Fatalf("Unable to delete index: %s", err)
+	}
+	for _, token := range []string{"token1", "token2"} {
+		if _, err := st.Get(token); err == nil {
+			t.Errorf("Token %s has not been deleted from the memory", token)
+		}
+	}
+	if _, err := st.Get("token3"); err != nil {
+		t.Errorf("Token out of the index must be kept: %s", err)
+	}
+}
+
 func createStorage(maxSize int) storage.Adapter {
 	return NewStorage(maxSize)
 }
@@ -0,0 +1,42 @@
+package storage
+
+// Namespace returns the adapter storing the tokens in the given adapter with the keys prefixed by
+// the namespace, so several verifiers can share one adapter. The returned adapter implements
+// the Indexer if the given adapter does.
+func Namespace(a Adapter, namespace string) Adapter {
+	n := namespaced{adapter: a, prefix: namespace + ":"}
+	if i, ok := a.(Indexer); ok {
+		return namespacedIndexer{namespaced: n, indexer: i}
+	}
+	return n
+}
+
+type namespaced struct {
+	adapter Adapter
+	prefix  string
+}
+
+func (n namespaced) Set(token string, expire int64, introspect []byte) error {
+	return n.adapter.Set(n.prefix+token, expire, introspect)
+}
+
+func (n namespaced) Get(token string) ([]byte, error) {
+	return n.adapter.Get(n.prefix + token)
+}
+
+func (n namespaced) Delete(token string) error {
+	return n.adapter.Delete(n.prefix + token)
+}
+
+type namespacedIndexer struct {
+	namespaced
+	indexer Indexer
+}
+
+func (n namespacedIndexer) Index(key string, token string, expire int64) error {
+	return n.indexer.Index(n.prefix+key, n.prefix+token, expire)
+}
+
+func (n namespacedIndexer) DeleteIndex(key string) error {
+	return n.indexer.DeleteIndex(n.prefix + key)
+}
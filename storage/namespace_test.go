@@ -0,0 +1,42 @@
+package storage_test
+
+import (
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	shared := memory.NewStorage(memory.MaxSize)
+	first := storage.Namespace(shared, "first")
+	second := storage.Namespace(shared, "second")
+	expire := time.Now().Add(time.Hour).Unix()
+
+	if err := first.Set("token", expire, []byte("first")); err != nil {
+		t.Fatalf("Unable to set token: %s", err)
+	}
+	if _, err := second.Get("token"); err != storage.ErrTokenNotExists {
+		t.Errorf("Token must not be visible in another namespace, got [%v]", err)
+	}
+
+	indexer, ok := second.(storage.Indexer)
+	if !ok {
+		t.Fatal("Namespace must implement the Indexer of the memory storage")
+	}
+	if err := second.Set("token", expire, []byte("second")); err != nil {
+		t.Fatalf("Unable to set token: %s", err)
+	}
+	if err := indexer.Index("sub:user", "token", expire); err != nil {
+		t.Fatalf("Unable to index token: %s", err)
+	}
+	if err := indexer.DeleteIndex("sub:user"); err != nil {
+		t.Fatalf("Unable to delete index: %s", err)
+	}
+	if _, err := second.Get("token"); err != storage.ErrTokenNotExists {
+		t.Errorf("Indexed token must be deleted, got [%v]", err)
+	}
+	if b, err := first.Get("token"); err != nil || string(b) != "first" {
+		t.Errorf("Token of another namespace must be kept, got [%s] [%v]", b, err)
+	}
+}
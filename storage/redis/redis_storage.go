@@ -1,7 +1,6 @@
 package redis
 
 import (
-	"errors"
 	"fmt"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang/storage"
 	"github.com/go-redis/redis"
@@ -9,26 +8,39 @@ import (
 )
 
 const (
+	// ErrorTokenNotExists is the message of the storage.ErrTokenNotExists.
 	ErrorTokenNotExists = "token not exists"
 )
 
 type redisStorage struct {
 	redis *redis.Client
 	key   string
+	clock storage.Clock
 }
 
+// NewStorage creates the storage in the redis. The options may contain the string format of the keys
+// with the namespace, "jwt:%s" by default, and the storage.Clock used to calculate the TTL of the tokens.
 func NewStorage(client *redis.Client, options ...interface{}) (storage.Adapter, error) {
 	key := "jwt:%s"
-	if len(options) > 0 {
-		switch options[0].(type) {
+	var clock storage.Clock = systemClock{}
+	for _, o := range options {
+		switch o := o.(type) {
 		case string:
-			key = options[0].(string)
+			key = o
+		case storage.Clock:
+			clock = o
 		default:
 			panic("Invalid options type for namespace")
 		}
 	}
 
-	return redisStorage{redis: client, key: key}, nil
+	return redisStorage{redis: client, key: key, clock: clock}, nil
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
 }
 
 func (tsr *redisStorage) buildKey(t string) string {
@@ -36,7 +48,11 @@ func (tsr *redisStorage) buildKey(t string) string {
 }
 
 func (tsr redisStorage) Set(token string, expire int64, introspect []byte) error {
-	duration := time.Unix(expire, 0).Sub(time.Now())
+	duration := time.Unix(expire, 0).Sub(tsr.clock.Now())
+	if duration <= 0 {
+		// The zero expiration means the key never expires in the redis.
+		return tsr.Delete(token)
+	}
 	if err := tsr.redis.Set(tsr.buildKey(token), introspect, duration); err.Err() != nil {
 		return err.Err()
 	}
@@ -45,8 +61,11 @@ func (tsr redisStorage) Set(token string, expire int64, introspect []byte) error
 
 func (tsr redisStorage) Get(token string) ([]byte, error) {
 	res := tsr.redis.Get(tsr.buildKey(token))
+	if res.Err() == redis.Nil {
+		return nil, storage.ErrTokenNotExists
+	}
 	if res.Err() != nil {
-		return nil, errors.New(ErrorTokenNotExists)
+		return nil, res.Err()
 	}
 	b, err := res.Bytes()
 	if err != nil {
@@ -59,3 +78,32 @@ func (tsr redisStorage) Delete(token string) error {
 	err := tsr.redis.Del(tsr.buildKey(token))
 	return err.Err()
 }
+
+func (tsr redisStorage) Index(key string, token string, expire int64) error {
+	k := tsr.buildKey("index:" + key)
+	if err := tsr.redis.SAdd(k, token).Err(); err != nil {
+		return err
+	}
+	// The index must live as long as the longest living token in it.
+	duration := time.Unix(expire, 0).Sub(tsr.clock.Now())
+	if duration <= 0 {
+		return nil
+	}
+	if ttl, err := tsr.redis.TTL(k).Result(); err == nil && ttl >= duration {
+		return nil
+	}
+	return tsr.redis.Expire(k, duration).Err()
+}
+
+func (tsr redisStorage) DeleteIndex(key string) error {
+	k := tsr.buildKey("index:" + key)
+	tokens, err := tsr.redis.SMembers(k).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{k}
+	for _, t := range tokens {
+		keys = append(keys, tsr.buildKey(t))
+	}
+	return tsr.redis.Del(keys...).Err()
+}
@@ -77,6 +77,24 @@ func TestRedeclareStorageKey(t *testing.T) {
 	}
 }
 
+func TestDeleteIndex(t *testing.T) {
+	st := createStorage()
+	tName := fmt.Sprintf("%d", time.Now().UnixNano())
+	exp := time.Now().Add(5 * time.Second).Unix()
+	st.Set(tName, exp, []byte(tName))
+
+	indexer := st.(storage.Indexer)
+	if err := indexer.Index("sub:"+tName, tName, exp); err != nil {
+		t.Errorf("Unable to index token in the redis: %s", err.Error())
+	}
+	if err := indexer.DeleteIndex("sub:" + tName); err != nil {
+		t.Errorf("Unable to delete index from the redis: %s", err.Error())
+	}
+	if _, err := st.Get(tName); err == nil {
+		t.Error("Indexed token has not been deleted from the redis")
+	}
+}
+
 func createStorage() storage.Adapter {
 	client := redis.NewClient(&redis.Options{
 		Addr: "localhost:6379",
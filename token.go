@@ -0,0 +1,75 @@
+package jwtverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// tokenResponse repeats the structure of the successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    json.Number `json:"expires_in"`
+}
+
+// retrieveToken sends the token request with the given grant parameters to the token endpoint.
+func (j *JwtVerifier) retrieveToken(ctx context.Context, form url.Values) (*Token, error) {
+	if err := checkEndpoint("token", j.config.endpoint.tokenURL); err != nil {
+		return nil, err
+	}
+	body, err := j.postForm(ctx, j.config.endpoint.tokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(body, tr); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot decode token: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+	extra := make(map[string]interface{})
+	_ = json.Unmarshal(body, &extra)
+
+	t := &oauth2.Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if sec, err := tr.ExpiresIn.Int64(); err == nil && sec > 0 {
+		t.Expiry = j.now().Add(time.Duration(sec) * time.Second)
+	}
+	j.lineage.add(t.RefreshToken, t.AccessToken)
+	return &Token{Token: t.WithExtra(extra)}, nil
+}
+
+// postForm sends the form authenticated with the configured client authentication method to the endpoint
+// and returns the body of the successful response.
+func (j *JwtVerifier) postForm(ctx context.Context, endpointURL string, form url.Values) ([]byte, error) {
+	r, err := j.do(ctx, func() (*http.Request, error) {
+		return j.newFormRequest(endpointURL, form)
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot read response: %v", err)
+	}
+	if code := r.StatusCode; code < 200 || code > 299 {
+		return nil, newRetrieveError(r, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,99 @@
+package jwtverifier
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// tokenExchangeGrantType is the grant type of the token exchange request.
+const tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Token type identifiers used by the token exchange.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc8693#section-3
+const (
+	AccessTokenType  = "urn:ietf:params:oauth:token-type:access_token"
+	RefreshTokenType = "urn:ietf:params:oauth:token-type:refresh_token"
+	IdTokenType      = "urn:ietf:params:oauth:token-type:id_token"
+	JwtTokenType     = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenExchangeRequest describes the token exchange request used to obtain a token for another
+// service acting on behalf of the subject.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc8693#section-2.1
+type TokenExchangeRequest struct {
+	// SubjectToken represents the identity of the party on behalf of whom the request is being made.
+	SubjectToken string
+
+	// SubjectTokenType is the type of the SubjectToken, the AccessTokenType is used if it's empty.
+	SubjectTokenType string
+
+	// ActorToken optionally represents the identity of the acting party.
+	ActorToken string
+
+	// ActorTokenType is the type of the ActorToken, the AccessTokenType is used if it's empty.
+	ActorTokenType string
+
+	// Audience lists the logical names of the target services where the token is intended to be used.
+	Audience []string
+
+	// Resource lists the URIs of the target services where the token is intended to be used.
+	Resource []string
+
+	// Scopes specifies the requested scopes of the issued token.
+	Scopes []string
+
+	// RequestedTokenType is the type of the requested token, the authorization server decides if it's empty.
+	RequestedTokenType string
+}
+
+// TokenExchange trades the subject token, e.g. the incoming access token of the user, for a new token
+// intended for another service. It returns the issued token and its issued_token_type.
+func (j *JwtVerifier) TokenExchange(ctx context.Context, r TokenExchangeRequest) (*Token, string, error) {
+	if r.SubjectToken == "" {
+		return nil, "", errors.New("jwtverifier: subject token is required for the token exchange")
+	}
+
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {r.SubjectToken},
+		"subject_token_type": {r.SubjectTokenType},
+	}
+	if r.SubjectTokenType == "" {
+		form.Set("subject_token_type", AccessTokenType)
+	}
+	if r.ActorToken != "" {
+		form.Set("actor_token", r.ActorToken)
+		form.Set("actor_token_type", r.ActorTokenType)
+		if r.ActorTokenType == "" {
+			form.Set("actor_token_type", AccessTokenType)
+		}
+	}
+	if r.RequestedTokenType != "" {
+		form.Set("requested_token_type", r.RequestedTokenType)
+	}
+	if len(r.Scopes) > 0 {
+		form.Set("scope", strings.Join(r.Scopes, " "))
+	}
+	for _, a := range r.Audience {
+		form.Add("audience", a)
+	}
+	for _, res := range r.Resource {
+		form.Add("resource", res)
+	}
+
+	t, err := j.retrieveToken(ctx, form)
+	if err != nil {
+		return nil, "", err
+	}
+	issued, _ := t.Extra("issued_token_type").(string)
+	if issued == "" {
+		return nil, "", errors.New("oauth2: server response missing issued_token_type")
+	}
+	return t, issued, nil
+}
@@ -0,0 +1,74 @@
+package jwtverifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenExchange(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		for key, want := range map[string]string{
+			"grant_type":         "urn:ietf:params:oauth:grant-type:token-exchange",
+			"subject_token":      "user-token",
+			"subject_token_type": AccessTokenType,
+			"actor_token":        "service-token",
+			"actor_token_type":   AccessTokenType,
+			"audience":           "service-b",
+			"resource":           "https://service-b/api",
+			"scope":              "read",
+		} {
+			if got := r.PostForm.Get(key); got != want {
+				t.Errorf("Unexpected %s parameter %q, want %q", key, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "exchanged-token", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token", "token_type": "bearer", "expires_in": 60}`))
+	}))
+	defer ts.Close()
+
+	tok, issued, err := createJwtVerifier(ts.URL).TokenExchange(context.Background(), TokenExchangeRequest{
+		SubjectToken: "user-token",
+		ActorToken:   "service-token",
+		Audience:     []string{"service-b"},
+		Resource:     []string{"https://service-b/api"},
+		Scopes:       []string{"read"},
+	})
+	if err != nil {
+		t.Fatalf("unable to exchange token: %s", err)
+	}
+	if tok.AccessToken != "exchanged-token" || issued != AccessTokenType {
+		t.Errorf("Unexpected exchanged token %#v of type %q", tok.Token, issued)
+	}
+}
+
+func TestTokenExchange_MissingIssuedTokenType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "exchanged-token", "token_type": "bearer"}`))
+	}))
+	defer ts.Close()
+
+	_, _, err := createJwtVerifier(ts.URL).TokenExchange(context.Background(), TokenExchangeRequest{SubjectToken: "user-token"})
+	if err == nil {
+		t.Error("expected error from missing issued_token_type")
+	}
+}
+
+func TestIntrospect_DelegationChain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"active":true,"client_id":"CLIENT_ID","sub":"user","act":{"sub":"service-a","act":{"sub":"gateway"}}}`))
+	}))
+	defer ts.Close()
+
+	tok, err := createJwtVerifier(ts.URL).Introspect(context.Background(), "90d64460d14870c08c81352a05dedd3465940a7c")
+	if err != nil {
+		t.Fatalf("unable to introspect token: %s", err)
+	}
+	chain := tok.Actors()
+	if len(chain) != 2 || chain[0].Sub != "service-a" || chain[1].Sub != "gateway" {
+		t.Errorf("Unexpected delegation chain %#v", chain)
+	}
+}
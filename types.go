@@ -1,6 +1,7 @@
 package jwtverifier
 
 import (
+	"encoding/json"
 	"fmt"
 	"golang.org/x/oauth2"
 	"net/http"
@@ -12,6 +13,10 @@ import (
 // - https://www.ory.sh/docs/hydra/sdk/api#schemaoauth2tokenintrospection
 // - https://www.iana.org/assignments/jwt/jwt.xhtml
 type IntrospectToken struct {
+	// Act is the actor claim which identifies the party acting on behalf of the subject after a token exchange.
+	// The nested actors describe the delegation chain.
+	Act *Actor `json:"act,omitempty"`
+
 	// Active is a boolean indicator of whether or not the presented token is currently active.
 	// The specifics of a token's \"active\" state will vary depending on the implementation of the authorization server
 	// and the information it keeps about its tokens, but a \"true\" value return for the \"active\" property will
@@ -54,25 +59,94 @@ type IntrospectToken struct {
 	// TokenType is the introspected token's type, for example `access_token` or `refresh_token`.
 	TokenType string `json:"token_type"`
 
+	// Stale is set if the cached introspection is returned after its soft TTL without the revalidation,
+	// see CachePolicy.
+	Stale bool `json:"-"`
+
 	// Username is a human-readable identifier for the resource owner who authorized this token.
 	Username string `json:"username,omitempty"`
 }
 
+// Actor repeats the structure of the actor claim of the delegated token.
+//
+// See more at:
+// - https://tools.ietf.org/html/rfc8693#section-4.1
+type Actor struct {
+	// Sub is the subject of the acting party.
+	Sub string `json:"sub"`
+
+	// Iss is the issuer of the acting party subject.
+	Iss string `json:"iss,omitempty"`
+
+	// ClientID is the client identifier of the acting party.
+	ClientID string `json:"client_id,omitempty"`
+
+	// Act is the prior actor of the delegation chain.
+	Act *Actor `json:"act,omitempty"`
+}
+
+// Actors returns the delegation chain of the token starting from the current actor.
+func (t *IntrospectToken) Actors() []*Actor {
+	var chain []*Actor
+	for a := t.Act; a != nil; a = a.Act {
+		chain = append(chain, a)
+	}
+	return chain
+}
+
 // IdToken based at JWT claims.
 //
 // See more at:
 // - https://www.iana.org/assignments/jwt/jwt.xhtml
+// - https://openid.net/specs/openid-connect-core-1_0.html#IDToken
 type IdToken struct {
+	Acr      string   `json:"acr,omitempty"`
+	Amr      []string `json:"amr,omitempty"`
 	AtHash   string   `json:"at_hash"`
-	Aud      []string `json:"aud"`
+	Aud      Audience `json:"aud"`
 	AuthTime int      `json:"auth_time"`
+	Azp      string   `json:"azp,omitempty"`
 	Exp      int64    `json:"exp"`
 	Iat      int      `json:"iat"`
 	Iss      string   `json:"iss"`
 	Jti      string   `json:"jti"`
+	Nbf      int64    `json:"nbf,omitempty"`
 	Nonce    string   `json:"nonce"`
 	Rat      int      `json:"rat"`
+	Sid      string   `json:"sid,omitempty"`
 	Sub      string   `json:"sub"`
+
+	// payload is the verified token payload used to decode the custom claims by Claims.
+	payload []byte
+}
+
+// Audience contains a list of the token's intended audiences. It's decoded from either
+// a single string or an array of strings, as allowed by JWT.
+type Audience []string
+
+// UnmarshalJSON decodes the audience from a single string or an array of strings.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = l
+	return nil
+}
+
+// Contains checks that the audience is listed.
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
 }
 
 // UserInfo based at JWT claims.
@@ -92,14 +166,45 @@ type UserInfo struct {
 // Token defined structure of oauth2.Token
 type Token struct {
 	*oauth2.Token
+
+	// RefreshTokenRotated reports that the authorization server issued a new refresh token
+	// on refresh, so the previous one must be replaced.
+	RefreshTokenRotated bool
 }
 
-// RetrieveError defined the structure of the error response to the oauth server
+// RetrieveError defined the structure of the error response to the oauth server.
+// It's returned by every endpoint of the authorization server responding with the non-2xx status.
 type RetrieveError struct {
 	Response *http.Response
 	Body     []byte
+
+	// ErrorCode is the OAuth 2.0 error code from the response body, for example `invalid_grant`.
+	ErrorCode string
+
+	// ErrorDescription is the human-readable description of the error from the response body.
+	ErrorDescription string
 }
 
 func (r *RetrieveError) Error() string {
 	return fmt.Sprintf("oauth2: cannot fetch token: %v\nResponse: %s", r.Response.Status, r.Body)
 }
+
+// Is reports whether the target is *RetrieveError with the same OAuth 2.0 error code, so the errors
+// can be checked with errors.Is(err, &RetrieveError{ErrorCode: "invalid_grant"}).
+func (r *RetrieveError) Is(target error) bool {
+	t, ok := target.(*RetrieveError)
+	return ok && t.ErrorCode != "" && t.ErrorCode == r.ErrorCode
+}
+
+// newRetrieveError creates the error of the failed response and decodes the OAuth 2.0 error from its body.
+func newRetrieveError(r *http.Response, body []byte) *RetrieveError {
+	e := &RetrieveError{Response: r, Body: body}
+	var oe struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &oe); err == nil {
+		e.ErrorCode, e.ErrorDescription = oe.Error, oe.ErrorDescription
+	}
+	return e
+}